@@ -0,0 +1,103 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+)
+
+// sessionConnector wraps a driver.Connector so that a set of statements
+// are executed on every new physical connection before it is handed to
+// the connection pool
+type sessionConnector struct {
+	driver.Connector
+	statements []string
+}
+
+// Connect implements driver.Connector
+func (c *sessionConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < len(c.statements); i++ {
+		if err := execOnConn(ctx, conn, c.statements[i]); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to execute session initialisation statement '%s': '%s'", c.statements[i], err)
+		}
+	}
+	return conn, nil
+}
+
+// dsnConnector is a driver.Connector for drivers which do not implement
+// driver.DriverContext
+type dsnConnector struct {
+	dsn    string
+	driver driver.Driver
+}
+
+// Connect implements driver.Connector
+func (c *dsnConnector) Connect(_ context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+// Driver implements driver.Connector
+func (c *dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// execOnConn executes the :query parameter without arguments on a raw
+// driver connection
+func execOnConn(ctx context.Context, conn driver.Conn, query string) error {
+	if execer, ok := conn.(driver.ExecerContext); ok {
+		_, err := execer.ExecContext(ctx, query, nil)
+		if err != driver.ErrSkip {
+			return err
+		}
+	}
+	stmt, err := conn.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	if execer, ok := stmt.(driver.StmtExecContext); ok {
+		_, err = execer.ExecContext(ctx, nil)
+		return err
+	}
+	_, err = stmt.Exec(nil)
+	return err
+}
+
+// newConnector returns a driver.Connector for the driver named :driverName
+// which executes the :sessionInit statements on every new connection
+func newConnector(driverName, dsn string, sessionInit []string) (driver.Connector, error) {
+	registeredConnection, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+	registeredDriver := registeredConnection.Driver()
+	registeredConnection.Close()
+	var connector driver.Connector
+	if driverContext, ok := registeredDriver.(driver.DriverContext); ok {
+		if connector, err = driverContext.OpenConnector(dsn); err != nil {
+			return nil, err
+		}
+	} else {
+		connector = &dsnConnector{dsn: dsn, driver: registeredDriver}
+	}
+	return &sessionConnector{Connector: connector, statements: sessionInit}, nil
+}
+
+// open returns a new connection pool as configured by the :options parameter
+func open(options Options) (*sql.DB, error) {
+	dsn := generateDSN(options)
+	if len(options.SessionInit) == 0 {
+		return sql.Open(options.Driver, dsn)
+	}
+	connector, err := newConnector(options.Driver, dsn, options.SessionInit)
+	if err != nil {
+		return nil, err
+	}
+	return sql.OpenDB(connector), nil
+}
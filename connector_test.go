@@ -0,0 +1,58 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/suite"
+)
+
+type ConnectorTests struct {
+	suite.Suite
+}
+
+func TestConnector(t *testing.T) {
+	suite.Run(t, &ConnectorTests{})
+}
+
+func (s *ConnectorTests) Test_newConnector() {
+	db, mock, err := sqlmock.NewWithDSN("__new_connector", sqlmock.MonitorPingsOption(true))
+	s.Nil(err)
+	defer db.Close()
+	mock.ExpectExec("SET time_zone = '\\+00:00'").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("SET NAMES utf8mb4").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectPing()
+	connector, err := newConnector("sqlmock", "__new_connector", []string{
+		"SET time_zone = '+00:00'",
+		"SET NAMES utf8mb4",
+	})
+	s.Nil(err)
+	connection := sql.OpenDB(connector)
+	s.Nil(connection.Ping())
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *ConnectorTests) Test_newConnector_error() {
+	db, mock, err := sqlmock.NewWithDSN("__new_connector_error")
+	s.Nil(err)
+	defer db.Close()
+	mock.ExpectExec("SET search_path TO app").WillReturnError(fmt.Errorf("this is expected"))
+	mock.ExpectClose()
+	connector, err := newConnector("sqlmock", "__new_connector_error", []string{
+		"SET search_path TO app",
+	})
+	s.Nil(err)
+	connection := sql.OpenDB(connector)
+	err = connection.Ping()
+	s.NotNil(err)
+	s.Contains(err.Error(), "SET search_path TO app")
+	s.Contains(err.Error(), "this is expected")
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *ConnectorTests) Test_newConnector_unknownDriver() {
+	_, err := newConnector("__unknown_driver", "", []string{"SELECT 1"})
+	s.NotNil(err)
+}
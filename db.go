@@ -31,8 +31,10 @@ func Check(optionalConnectionName ...string) error {
 		if selectedConnection, exists = connection[DefaultConnectionName]; !exists {
 			return fmt.Errorf("connection with id '%s' does not exist", connectionName)
 		}
+		connectionName = DefaultConnectionName
 	}
 	if err := selectedConnection.Ping(); err != nil {
+		emit(EventCheckFailed, connectionName, err)
 		return err
 	}
 	return nil
@@ -45,8 +47,10 @@ func Close(optionalConnectionName ...string) error {
 		connectionName = optionalConnectionName[0]
 	}
 	if err := connection[connectionName].Close(); err != nil {
+		emit(EventCloseFailed, connectionName, err)
 		return fmt.Errorf("error while closing connection '%s': '%s'", connectionName, err)
 	}
+	emit(EventClosed, connectionName, nil)
 	return nil
 }
 
@@ -56,9 +60,12 @@ func CloseAll() []error {
 	errs := []error{}
 	for key, value := range connection {
 		if err := value.Close(); err != nil {
+			emit(EventCloseFailed, key, err)
 			errs = append(errs, fmt.Errorf("error while closing connection '%s': '%s'", key, err))
 		} else {
+			emit(EventClosed, key, nil)
 			delete(connection, key)
+			delete(connectionDetails, key)
 		}
 	}
 	if len(errs) > 0 {
@@ -92,6 +99,8 @@ func Import(existingConnection *sql.DB, optionalConnectionName ...string) error
 		return fmt.Errorf("unable to import connection with id '%s' - another connection with the same id already exists", connectionName)
 	}
 	connection[connectionName] = existingConnection
+	connectionDetails[connectionName] = details{driver: getDriverName(existingConnection)}
+	emit(EventImported, connectionName, nil)
 	return nil
 }
 
@@ -100,15 +109,23 @@ func Import(existingConnection *sql.DB, optionalConnectionName ...string) error
 func Init(options Options) error {
 	var err error
 	options.AssignDefaults()
-	if _, ok := connection[options.ConnectionName]; ok {
-		defer connection[options.ConnectionName].Close()
-	}
-	connection[options.ConnectionName], err = sql.Open(
-		options.Driver,
-		generateDSN(options),
-	)
+	existingConnection, replacing := connection[options.ConnectionName]
+	existingDetails := connectionDetails[options.ConnectionName]
+	newConnection, err := open(options)
 	if err != nil {
 		return err
 	}
+	connection[options.ConnectionName] = newConnection
+	connectionDetails[options.ConnectionName] = details{
+		driver:   options.Driver,
+		hostname: options.Hostname,
+	}
+	if replacing {
+		if err := existingConnection.Close(); err != nil {
+			emitWithDetails(EventCloseFailed, options.ConnectionName, existingDetails, err)
+		}
+		emitWithDetails(EventReplaced, options.ConnectionName, existingDetails, nil)
+	}
+	emit(EventRegistered, options.ConnectionName, nil)
 	return nil
 }
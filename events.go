@@ -0,0 +1,136 @@
+package db
+
+import (
+	"database/sql"
+	"sync"
+	"time"
+
+	mssql "github.com/denisenkom/go-mssqldb"
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+const (
+	// EventRegistered is emitted when Init registers a new connection
+	EventRegistered EventType = "registered"
+	// EventReplaced is emitted when Init replaces (and closes) an existing
+	// connection, it carries the details of the replaced connection
+	EventReplaced EventType = "replaced"
+	// EventImported is emitted when an existing connection is added via Import
+	EventImported EventType = "imported"
+	// EventClosed is emitted when a connection is closed via Close or CloseAll
+	EventClosed EventType = "closed"
+	// EventCloseFailed is emitted when a connection could not be closed
+	EventCloseFailed EventType = "close_failed"
+	// EventCheckFailed is emitted when Check fails to reach the database server
+	EventCheckFailed EventType = "check_failed"
+)
+
+var (
+	// connectionDetails holds details of registered connections that are
+	// not retrievable from a *sql.DB, keyed by connection name
+	connectionDetails = map[string]details{}
+	// subscribers holds the functions registered via Subscribe
+	subscribers     = []subscriber{}
+	subscribersLock sync.RWMutex
+	// subscriberID is the id assigned to the next subscriber
+	subscriberID int
+)
+
+// EventType identifies what happened to a connection
+type EventType string
+
+// Event describes a change in the lifecycle of a connection
+type Event struct {
+	// Type defines what happened to the connection
+	Type EventType `json:"type" yaml:"type"`
+	// ConnectionName is the local name of the connection
+	ConnectionName string `json:"connection_name" yaml:"connection_name"`
+	// Driver is the name of the driver used by the connection, this is
+	// empty for imported connections using an unrecognised driver
+	Driver string `json:"driver" yaml:"driver"`
+	// Hostname is the hostname of the database server, this is empty for
+	// imported connections
+	Hostname string `json:"hostname" yaml:"hostname"`
+	// Error holds the error which caused an EventCloseFailed or
+	// EventCheckFailed event
+	Error error `json:"-" yaml:"-"`
+	// Timestamp is the time at which the event happened
+	Timestamp time.Time `json:"timestamp" yaml:"timestamp"`
+}
+
+type details struct {
+	driver   string
+	hostname string
+}
+
+type subscriber struct {
+	id      int
+	handler func(Event)
+}
+
+// Subscribe registers the :handler parameter to be called with every
+// connection lifecycle event, the returned function removes the
+// subscription. Handlers are called synchronously in the order they
+// were subscribed and should not block
+func Subscribe(handler func(Event)) func() {
+	subscribersLock.Lock()
+	defer subscribersLock.Unlock()
+	subscriberID++
+	id := subscriberID
+	subscribers = append(subscribers, subscriber{id: id, handler: handler})
+	return func() {
+		subscribersLock.Lock()
+		defer subscribersLock.Unlock()
+		for i := 0; i < len(subscribers); i++ {
+			if subscribers[i].id == id {
+				subscribers = append(subscribers[:i:i], subscribers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// emit sends an event of the :eventType parameter for the connection
+// named :connectionName to all subscribers
+func emit(eventType EventType, connectionName string, err error) {
+	emitWithDetails(eventType, connectionName, connectionDetails[connectionName], err)
+}
+
+// emitWithDetails sends an event of the :eventType parameter for the
+// connection named :connectionName described by the :connectionDetail
+// parameter to all subscribers, this is used for connections which are no
+// longer registered
+func emitWithDetails(eventType EventType, connectionName string, connectionDetail details, err error) {
+	subscribersLock.RLock()
+	handlers := make([]func(Event), 0, len(subscribers))
+	for i := 0; i < len(subscribers); i++ {
+		handlers = append(handlers, subscribers[i].handler)
+	}
+	subscribersLock.RUnlock()
+	event := Event{
+		Type:           eventType,
+		ConnectionName: connectionName,
+		Driver:         connectionDetail.driver,
+		Hostname:       connectionDetail.hostname,
+		Error:          err,
+		Timestamp:      time.Now(),
+	}
+	for i := 0; i < len(handlers); i++ {
+		handlers[i](event)
+	}
+}
+
+// getDriverName returns the name of the driver used by the :existingConnection
+// parameter, or an empty string if the driver is not one of SupportedDrivers
+func getDriverName(existingConnection *sql.DB) string {
+	switch existingConnection.Driver().(type) {
+	case *mysql.MySQLDriver, mysql.MySQLDriver:
+		return DriverMySQL
+	case *pq.Driver:
+		return DriverPostgreSQL
+	case *mssql.Driver:
+		return DriverMSSQL
+	}
+	return ""
+}
@@ -0,0 +1,96 @@
+package db
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/suite"
+)
+
+type EventsTests struct {
+	suite.Suite
+}
+
+func TestEvents(t *testing.T) {
+	suite.Run(t, &EventsTests{})
+}
+
+func (s *EventsTests) TestSubscribe() {
+	var events []Event
+	unsubscribe := Subscribe(func(event Event) {
+		events = append(events, event)
+	})
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	s.Nil(err)
+	mock.ExpectPing().WillReturnError(fmt.Errorf("this is expected"))
+	mock.ExpectClose()
+	s.Nil(Import(db, "__events_subscribe"))
+	s.NotNil(Check("__events_subscribe"))
+	s.Nil(Close("__events_subscribe"))
+	unsubscribe()
+	s.Nil(Import(db, "__events_subscribe_unsubscribed"))
+	s.Nil(mock.ExpectationsWereMet())
+	s.Len(events, 3)
+	s.Equal(EventImported, events[0].Type)
+	s.Equal("__events_subscribe", events[0].ConnectionName)
+	s.Equal(EventCheckFailed, events[1].Type)
+	s.Equal("this is expected", events[1].Error.Error())
+	s.Equal(EventClosed, events[2].Type)
+	s.False(events[2].Timestamp.IsZero())
+}
+
+func (s *EventsTests) TestSubscribe_init() {
+	var events []Event
+	unsubscribe := Subscribe(func(event Event) {
+		events = append(events, event)
+	})
+	defer unsubscribe()
+	options := Options{
+		ConnectionName: "__events_subscribe_init",
+		Driver:         DriverPostgreSQL,
+		Hostname:       "_host._name",
+	}
+	s.Nil(Init(options))
+	options.Driver = DriverMySQL
+	options.Hostname = "_other._host"
+	s.Nil(Init(options))
+	s.Len(events, 3)
+	s.Equal(EventRegistered, events[0].Type)
+	s.Equal(EventReplaced, events[1].Type)
+	s.Equal(DriverPostgreSQL, events[1].Driver)
+	s.Equal("_host._name", events[1].Hostname)
+	s.Equal(EventRegistered, events[2].Type)
+	s.Equal(DriverMySQL, events[2].Driver)
+	s.Equal("_other._host", events[2].Hostname)
+}
+
+func (s *EventsTests) TestSubscribe_initCloseFailed() {
+	var events []Event
+	unsubscribe := Subscribe(func(event Event) {
+		events = append(events, event)
+	})
+	defer unsubscribe()
+	db, mock, err := sqlmock.New()
+	s.Nil(err)
+	mock.ExpectClose().WillReturnError(fmt.Errorf("this is expected"))
+	s.Nil(Import(db, "__events_subscribe_init_close_failed"))
+	s.Nil(Init(Options{ConnectionName: "__events_subscribe_init_close_failed"}))
+	s.Nil(mock.ExpectationsWereMet())
+	s.Len(events, 4)
+	s.Equal(EventImported, events[0].Type)
+	s.Equal(EventCloseFailed, events[1].Type)
+	s.Equal("this is expected", events[1].Error.Error())
+	s.Equal("", events[1].Driver)
+	s.Equal(EventReplaced, events[2].Type)
+	s.Equal("", events[2].Driver)
+	s.Equal(EventRegistered, events[3].Type)
+	s.Equal(DriverMySQL, events[3].Driver)
+}
+
+func (s *EventsTests) Test_getDriverName() {
+	db, _, err := sqlmock.New()
+	s.Nil(err)
+	defer db.Close()
+	s.Equal("", getDriverName(db))
+}
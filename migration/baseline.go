@@ -0,0 +1,118 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Baseline acquires the migration lock, ensures the migrations table
+// exists and records the migrations with a version up to and including the
+// :version parameter as StatusBaselined without executing them, so that a
+// database whose schema already reflects them can be adopted. Migrations
+// which have already been recorded are skipped and unversioned migrations
+// are never baselined. The returned error is the same as Report.Error
+func (r *Runner) Baseline(ctx context.Context, version int64) (report *Report, err error) {
+	report = &Report{}
+	migrations := make(Migrations, len(r.Migrations))
+	copy(migrations, r.Migrations)
+	sort.Sort(migrations)
+	if err := migrations.Validate(); err != nil {
+		report.Error = err
+		return report, err
+	}
+	count, found := 0, false
+	for i := 0; i < len(migrations); i++ {
+		migrationVersion, versioned := migrations[i].Version()
+		if !versioned || migrationVersion > version {
+			break
+		}
+		count, found = i+1, migrationVersion == version
+	}
+	if !found {
+		err := fmt.Errorf("failed to find migration with version %v to baseline", version)
+		report.Error = err
+		return report, err
+	}
+	migrations = migrations[:count]
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		report.Pending = migrations
+		report.Error = err
+		return report, err
+	}
+	defer func() {
+		if unlockErr := unlock(); unlockErr != nil && err == nil {
+			report.Error = unlockErr
+			err = unlockErr
+		}
+	}()
+	if err := r.ensureTable(ctx); err != nil {
+		report.Pending = migrations
+		report.Error = err
+		return report, err
+	}
+	for i := 0; i < len(migrations); i++ {
+		migration := migrations[i]
+		if err := ctx.Err(); err != nil {
+			report.Pending = migrations[i:]
+			report.Error = err
+			return report, err
+		}
+		err := r.Engine.Baseline(ctx, migration)
+		if err == NoErrAlreadyApplied {
+			report.Skipped = append(report.Skipped, migration)
+			continue
+		} else if !report.succeeded(err) {
+			report.Failed = migration
+			report.Pending = migrations[i+1:]
+			report.Error = err
+			return report, err
+		}
+		report.Baselined = append(report.Baselined, migration)
+	}
+	return report, nil
+}
+
+// Baseline records the :m parameter in the migrations table as
+// StatusBaselined without executing it, NoErrAlreadyApplied is returned if
+// the migration has already been applied or baselined and a *StateError
+// if it has been recorded in any other status than StatusRolledBack. The
+// attempt is recorded in the log table
+func (e *Engine) Baseline(ctx context.Context, m *Migration) error {
+	started := time.Now()
+	err := e.baseline(ctx, m)
+	if err == NoErrAlreadyApplied {
+		return err
+	}
+	return e.log(OperationBaseline, m, started, err)
+}
+
+func (e *Engine) baseline(ctx context.Context, m *Migration) error {
+	id, status, err := e.recorded(ctx, m)
+	if err != nil {
+		return fmt.Errorf("[baseline:%s] failed to check if migration has already been applied: '%s'", m.Name, err)
+	} else if id != 0 && isApplied(status) {
+		return NoErrAlreadyApplied
+	} else if id != 0 && status != StatusRolledBack {
+		return &StateError{Operation: "baseline", Name: m.Name, Status: status}
+	}
+	connection, finish, err := e.begin(ctx, true)
+	if err != nil {
+		return fmt.Errorf("[baseline:%s] failed to begin transaction: '%s'", m.Name, err)
+	}
+	if err = e.record(ctx, connection, m, id, nil); err != nil {
+		finish(false)
+		return fmt.Errorf("[baseline:%s] failed to insert migration entry into migration table '%s': '%s'", m.Name, e.TableName, err)
+	}
+	_, err = connection.ExecContext(ctx, e.query("UPDATE %s SET applied_at = ?, status = ? WHERE id = ?"), time.Now(), StatusBaselined, m.ID)
+	if err != nil {
+		finish(false)
+		return fmt.Errorf("[baseline:%s] failed to indicate baseline for migration: '%s'", m.Name, err)
+	}
+	if err = finish(true); err != nil {
+		return fmt.Errorf("[baseline:%s] failed to commit baseline: '%s'", m.Name, err)
+	}
+	return nil
+}
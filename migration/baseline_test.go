@@ -0,0 +1,85 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/suite"
+)
+
+type BaselineTests struct {
+	suite.Suite
+}
+
+func TestBaseline(t *testing.T) {
+	suite.Run(t, &BaselineTests{})
+}
+
+func (s *BaselineTests) TestBaseline() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	mock.ExpectQuery("SELECT LOCK").WillReturnRows(sqlmock.NewRows([]string{"lock"}).AddRow(1))
+	expectEnsureTable(mock)
+	mock.ExpectQuery("SELECT id, status FROM migrations").WithArgs("1_a").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status"}).AddRow(1, StatusApplied))
+	mock.ExpectQuery("SELECT id, status FROM migrations").WithArgs("2_b").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status"}))
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO migrations").WithArgs("2_b", "UP b", "DOWN b", sqlmock.AnyArg(), StatusApplying, KindSQL, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	mock.ExpectExec("UPDATE migrations SET applied_at = \\$1, status = \\$2 WHERE id = \\$3").
+		WithArgs(sqlmock.AnyArg(), StatusBaselined, 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectLog(mock, "2_b", OperationBaseline, OutcomeSucceeded)
+	mock.ExpectExec("SELECT UNLOCK").WillReturnResult(sqlmock.NewResult(0, 0))
+	migrations := Migrations{New("3_c", "UP c", "DOWN c"), New("2_b", "UP b", "DOWN b"), New("1_a", "UP a", "DOWN a")}
+	report, err := NewRunner(NewEngine(testDialect{}, "migrations", connection), migrations).Baseline(context.Background(), 2)
+	s.Nil(err)
+	s.Len(report.Skipped, 1)
+	s.Equal("1_a", report.Skipped[0].Name)
+	s.Len(report.Baselined, 1)
+	s.Equal("2_b", report.Baselined[0].Name)
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *BaselineTests) TestBaseline_error_version() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	migrations := Migrations{New("1_a", "UP a", "DOWN a"), New("3_c", "UP c", "DOWN c")}
+	report, err := NewRunner(NewEngine(testDialect{}, "migrations", connection), migrations).Baseline(context.Background(), 2)
+	s.Contains(err.Error(), "failed to find migration with version 2 to baseline")
+	s.Equal(err, report.Error)
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *BaselineTests) TestBaseline_error_state() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	mock.ExpectQuery("SELECT id, status FROM migrations").WithArgs("1_a").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status"}).AddRow(1, StatusApplying))
+	expectLog(mock, "1_a", OperationBaseline, OutcomeFailed)
+	err = NewEngine(testDialect{}, "migrations", connection).Baseline(context.Background(), New("1_a", "UP a", "DOWN a"))
+	s.Equal(&StateError{Operation: "baseline", Name: "1_a", Status: StatusApplying}, err)
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *BaselineTests) TestBaseline_error_record() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	mock.ExpectQuery("SELECT id, status FROM migrations").WithArgs("1_a").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status"}))
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO migrations").WillReturnError(fmt.Errorf("this is expected"))
+	mock.ExpectRollback()
+	expectLog(mock, "1_a", OperationBaseline, OutcomeFailed)
+	err = NewEngine(testDialect{}, "migrations", connection).Baseline(context.Background(), New("1_a", "UP a", "DOWN a"))
+	s.Equal("[baseline:1_a] failed to insert migration entry into migration table 'migrations': 'failed to insert initial row: 'this is expected''", err.Error())
+	s.Nil(mock.ExpectationsWereMet())
+}
@@ -0,0 +1,63 @@
+package migration
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/usvc/go-db"
+)
+
+// Checksum returns the hex-encoded SHA-256 checksum of the scripts of the
+// :m parameter, the scripts are canonicalised for the :driver parameter
+// with CanonicalTokens so that changes to comments and whitespace do not
+// change the checksum
+func Checksum(driver string, m *Migration) string {
+	hash := sha256.New()
+	hash.Write([]byte(strings.Join(CanonicalTokens(driver, m.Up), "\x00")))
+	hash.Write([]byte{0, 0})
+	hash.Write([]byte(strings.Join(CanonicalTokens(driver, m.Down), "\x00")))
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// CanonicalTokens returns the tokens of the :script parameter without
+// comments and whitespace, string literals, quoted identifiers and
+// dollar-quoted bodies are returned as single tokens
+func CanonicalTokens(driver, script string) []string {
+	var tokens []string
+	scriptTokens := db.Tokenize(driver, script)
+	for i := 0; i < len(scriptTokens); i++ {
+		value := scriptTokens[i].Value
+		switch scriptTokens[i].Kind {
+		case db.TokenComment:
+			continue
+		case db.TokenString, db.TokenIdentifier:
+			tokens = append(tokens, value)
+			continue
+		}
+		for j := 0; j < len(value); {
+			c := value[j]
+			start := j
+			switch {
+			case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+				j++
+				continue
+			case isWordCharacter(c):
+				for j < len(value) && isWordCharacter(value[j]) {
+					j++
+				}
+			default:
+				j++
+			}
+			tokens = append(tokens, value[start:j])
+		}
+	}
+	return tokens
+}
+
+// isWordCharacter returns true if the :c parameter can be part of a
+// keyword, identifier or number
+func isWordCharacter(c byte) bool {
+	return c == '_' || c == '$' || c == '@' || c >= 0x80 ||
+		(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,32 @@
+package migration
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/usvc/go-db"
+)
+
+type ChecksumTests struct {
+	suite.Suite
+}
+
+func TestChecksum(t *testing.T) {
+	suite.Run(t, &ChecksumTests{})
+}
+
+func (s *ChecksumTests) TestChecksum() {
+	checksum := Checksum(db.DriverMySQL, New("a", "CREATE TABLE a (id INTEGER, name TEXT);", "DROP TABLE a;"))
+	s.Len(checksum, 64)
+	s.Equal(checksum, Checksum(db.DriverMySQL, New("b", "-- creates a\nCREATE TABLE a (\n\tid INTEGER,\n\tname TEXT\n) ;\n", "/* drops a */ DROP TABLE a;")))
+	s.NotEqual(checksum, Checksum(db.DriverMySQL, New("a", "CREATE TABLE a (id BIGINT, name TEXT);", "DROP TABLE a;")))
+	s.NotEqual(checksum, Checksum(db.DriverMySQL, New("a", "CREATE TABLE a (id INTEGER, name TEXT);", "")))
+	s.NotEqual(checksum, Checksum(db.DriverMySQL, New("a", "CREATE TABLE a (id INTEGER, name TEXT); DROP TABLE a;", "")))
+}
+
+func (s *ChecksumTests) TestCanonicalTokens() {
+	s.Equal([]string{"INSERT", "INTO", "a", "VALUES", "(", "'b  -- c'", ",", "$1", ")", ";"}, CanonicalTokens(db.DriverPostgreSQL, "INSERT INTO a\n\tVALUES ('b  -- c',$1); -- d"))
+	s.Equal([]string{"SELECT", "$$ a  b $$"}, CanonicalTokens(db.DriverPostgreSQL, "SELECT $$ a  b $$"))
+	s.Equal([]string{"SELECT", "[a  b]", "FROM", "c"}, CanonicalTokens(db.DriverMSSQL, "SELECT [a  b] /* x */ FROM c"))
+	s.Equal([]string{"SELECT", "`a  b`", "FROM", "c"}, CanonicalTokens(db.DriverMySQL, "SELECT `a  b` # x\nFROM c"))
+}
@@ -0,0 +1,71 @@
+package migration
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultLockTimeout is the duration a Runner waits for the migration
+	// lock when its LockTimeout is not set
+	DefaultLockTimeout = time.Minute
+	// DefaultStaleAfter is the duration after which a migration which is
+	// still being applied or rolled back is considered stale when a Runner
+	// does not set StaleAfter, see Runner.Repair
+	DefaultStaleAfter = time.Hour
+
+	StatusRollingBack = "rolling back"
+	StatusRolledBack  = "rolled back"
+	StatusApplying    = "applying"
+	StatusApplied     = "applied"
+	// StatusBaselined is recorded for migrations which were already
+	// reflected in the schema when the database was baselined, they are
+	// treated as applied but were never executed, see Runner.Baseline
+	StatusBaselined = "baselined"
+
+	// KindSQL is the kind of migrations which execute SQL scripts
+	KindSQL = "sql"
+	// KindGo is the kind of migrations which execute Go functions, see
+	// NewFunc
+	KindGo = "go"
+
+	// RepairApplied marks a stale migration as applied (or a migration
+	// which was being rolled back as still applied), see Runner.Repair
+	RepairApplied = "applied"
+	// RepairFailed marks a stale migration as failed so that it is
+	// reported by Validate and can be resolved
+	RepairFailed = "failed"
+	// RepairRemove removes a stale or failed migration from the migrations
+	// table so that it can be applied again
+	RepairRemove = "remove"
+)
+
+var (
+	NoErrAlreadyApplied = errors.New("migration has already been applied")
+	NoErrDoesNotExist   = errors.New("migration does not exist")
+	// ErrLockTimeout is returned when the migration lock could not be
+	// acquired in time, usually because another process is migrating
+	ErrLockTimeout = errors.New("timed out waiting for migration lock")
+)
+
+// StateError is returned when a migration is not in a state which allows
+// the requested operation, such as rolling back a migration which has not
+// been applied
+type StateError struct {
+	// Operation is the operation which was requested (eg. "rollback")
+	Operation string
+	// Name is the name of the migration
+	Name string
+	// Status is the recorded status of the migration, empty if the
+	// migration has not been recorded
+	Status string
+}
+
+// Error implements the error interface
+func (e *StateError) Error() string {
+	if len(e.Status) == 0 {
+		return fmt.Sprintf("[%s:%s] migration has not been recorded", e.Operation, e.Name)
+	}
+	return fmt.Sprintf("[%s:%s] migration cannot be processed in status '%s'", e.Operation, e.Name, e.Status)
+}
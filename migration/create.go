@@ -0,0 +1,148 @@
+package migration
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	// SchemeTimestamp prefixes the names of migrations created by
+	// NewMigrationFiles with the current UTC time as 20060102150405
+	SchemeTimestamp = "timestamp"
+	// SchemeSequence prefixes the names of migrations created by
+	// NewMigrationFiles with the version following the highest existing
+	// version, zero-padded to the width of its prefix (or SequenceWidth)
+	SchemeSequence = "sequence"
+	// SequenceWidth is the width of the prefix of the first migration
+	// created with SchemeSequence in a directory
+	SequenceWidth = 4
+)
+
+// MigrationTemplate is the text/template used by NewMigrationFiles to write
+// the upward and downward scripts of a migration, it is executed with a
+// MigrationTemplateData
+var MigrationTemplate = "-- {{.Direction}} migration {{.Name}}\n"
+
+// timeNow returns the current time used by SchemeTimestamp
+var timeNow = time.Now
+
+// MigrationTemplateData is passed to MigrationTemplate
+type MigrationTemplateData struct {
+	// Name is the full name of the migration, including its version
+	Name string
+	// Version is the version of the migration
+	Version int64
+	// Direction is either "up" or "down"
+	Direction string
+}
+
+// NewMigrationFiles creates the files of a migration named :name in the
+// directory :dir (which is created if it does not exist), prefixed with a
+// version according to the :scheme parameter (one of the Scheme*
+// constants), and returns the migration with its Path set. The scripts are
+// written from MigrationTemplate and the files are named so that
+// GetMigrationNamesFromFilenames accepts them. Versions are taken from the
+// migrations in the directory and its subdirectories, as NewFromDirectory
+// loads both, and an error is returned if a migration with the same version
+// already exists in them
+func NewMigrationFiles(dir, name, scheme string) (*Migration, error) {
+	if len(name) == 0 || strings.TrimLeft(name, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_-") != "" {
+		return nil, fmt.Errorf("failed to create migration '%s': names must only contain letters, digits, underscores and dashes", name)
+	}
+	if scheme != SchemeTimestamp && scheme != SchemeSequence {
+		return nil, fmt.Errorf("failed to create migration '%s': unknown versioning scheme '%s'", name, scheme)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create migrations directory '%s': '%s'", dir, err)
+	}
+	versions := map[int64]string{}
+	var latest int64
+	width := SequenceWidth
+	err := filepath.Walk(dir, func(_ string, fileInfo os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		filename := fileInfo.Name()
+		if fileInfo.IsDir() || !(strings.HasSuffix(filename, ".up.sql") || strings.HasSuffix(filename, ".down.sql")) {
+			return nil
+		}
+		existing := &Migration{Name: strings.TrimSuffix(strings.TrimSuffix(filename, ".up.sql"), ".down.sql")}
+		version, versioned := existing.Version()
+		if !versioned {
+			return nil
+		}
+		versions[version] = existing.Name
+		if version >= latest {
+			latest = version
+			width = strings.IndexAny(existing.Name, "_-.")
+			if width < 0 {
+				width = len(existing.Name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations directory '%s': '%s'", dir, err)
+	}
+	var prefix string
+	switch scheme {
+	case SchemeTimestamp:
+		prefix = timeNow().UTC().Format("20060102150405")
+	case SchemeSequence:
+		prefix = fmt.Sprintf("%0*d", width, latest+1)
+	}
+	version, _ := strconv.ParseInt(prefix, 10, 64)
+	if existing, ok := versions[version]; ok {
+		return nil, fmt.Errorf("failed to create migration '%s': migration '%s' already has version %v", name, existing, version)
+	}
+	migration := New(prefix+"_"+name, "", "")
+	migration.Path = filepath.Join(dir, migration.Name)
+	if migration.Up, err = renderMigrationTemplate(migration.Name, version, "up"); err != nil {
+		return nil, err
+	}
+	if migration.Down, err = renderMigrationTemplate(migration.Name, version, "down"); err != nil {
+		return nil, err
+	}
+	if err := createFile(migration.Path+".up.sql", migration.Up); err != nil {
+		return nil, err
+	}
+	if err := createFile(migration.Path+".down.sql", migration.Down); err != nil {
+		os.Remove(migration.Path + ".up.sql")
+		return nil, err
+	}
+	return migration, nil
+}
+
+// renderMigrationTemplate executes MigrationTemplate for the :direction of
+// the migration named :name
+func renderMigrationTemplate(name string, version int64, direction string) (string, error) {
+	tmpl, err := template.New("migration").Parse(MigrationTemplate)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse migration template: '%s'", err)
+	}
+	var script bytes.Buffer
+	if err := tmpl.Execute(&script, MigrationTemplateData{Name: name, Version: version, Direction: direction}); err != nil {
+		return "", fmt.Errorf("failed to execute migration template for '%s': '%s'", name, err)
+	}
+	return script.String(), nil
+}
+
+// createFile writes the :contents parameter to a new file at :path,
+// failing if the file already exists
+func createFile(path, contents string) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create migration file '%s': '%s'", path, err)
+	}
+	if _, err := file.WriteString(contents); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write migration file '%s': '%s'", path, err)
+	}
+	return file.Close()
+}
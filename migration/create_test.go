@@ -0,0 +1,106 @@
+package migration
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type CreateTests struct {
+	suite.Suite
+	directory string
+}
+
+func TestCreate(t *testing.T) {
+	suite.Run(t, &CreateTests{})
+}
+
+func (s *CreateTests) SetupTest() {
+	directory, err := ioutil.TempDir("", "create")
+	s.Nil(err)
+	s.directory = directory
+}
+
+func (s *CreateTests) TearDownTest() {
+	os.RemoveAll(s.directory)
+	timeNow = time.Now
+}
+
+func (s *CreateTests) TestNewMigrationFiles_sequence() {
+	migration, err := NewMigrationFiles(s.directory, "create_users", SchemeSequence)
+	s.Nil(err)
+	s.Equal("0001_create_users", migration.Name)
+	s.Equal(filepath.Join(s.directory, "0001_create_users"), migration.Path)
+	migration, err = NewMigrationFiles(s.directory, "add-email", SchemeSequence)
+	s.Nil(err)
+	s.Equal("0002_add-email", migration.Name)
+	up, err := ioutil.ReadFile(migration.Path + ".up.sql")
+	s.Nil(err)
+	s.Equal("-- up migration 0002_add-email\n", string(up))
+	migrations, err := NewFromDirectory(s.directory)
+	s.Nil(err)
+	s.Len(migrations, 2)
+	s.Equal("-- down migration 0001_create_users\n", migrations[0].Down)
+}
+
+func (s *CreateTests) TestNewMigrationFiles_sequenceWidth() {
+	s.Nil(ioutil.WriteFile(filepath.Join(s.directory, "9_a.up.sql"), nil, 0644))
+	s.Nil(ioutil.WriteFile(filepath.Join(s.directory, "9_a.down.sql"), nil, 0644))
+	s.Nil(ioutil.WriteFile(filepath.Join(s.directory, "README.md"), nil, 0644))
+	migration, err := NewMigrationFiles(s.directory, "b", SchemeSequence)
+	s.Nil(err)
+	s.Equal("10_b", migration.Name)
+	fileInfos, err := ioutil.ReadDir(s.directory)
+	s.Nil(err)
+	var filenames []string
+	for i := 0; i < len(fileInfos); i++ {
+		filenames = append(filenames, fileInfos[i].Name())
+	}
+	names, errs := GetMigrationNamesFromFilenames(filenames)
+	s.Len(errs, 1)
+	s.Equal([]string{"10_b", "9_a"}, names)
+}
+
+func (s *CreateTests) TestNewMigrationFiles_nested() {
+	s.Nil(os.Mkdir(filepath.Join(s.directory, "users"), 0755))
+	s.Nil(ioutil.WriteFile(filepath.Join(s.directory, "users", "0001_a.up.sql"), nil, 0644))
+	s.Nil(ioutil.WriteFile(filepath.Join(s.directory, "users", "0001_a.down.sql"), nil, 0644))
+	migration, err := NewMigrationFiles(s.directory, "b", SchemeSequence)
+	s.Nil(err)
+	s.Equal("0002_b", migration.Name)
+	s.Equal(filepath.Join(s.directory, "0002_b"), migration.Path)
+	migrations, err := NewFromDirectory(s.directory)
+	s.Nil(err)
+	s.Len(migrations, 2)
+}
+
+func (s *CreateTests) TestNewMigrationFiles_timestamp() {
+	timeNow = func() time.Time { return time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC) }
+	migration, err := NewMigrationFiles(filepath.Join(s.directory, "migrations"), "a", SchemeTimestamp)
+	s.Nil(err)
+	s.Equal("20200102030405_a", migration.Name)
+	version, versioned := migration.Version()
+	s.True(versioned)
+	s.Equal(int64(20200102030405), version)
+	_, err = NewMigrationFiles(filepath.Join(s.directory, "migrations"), "b", SchemeTimestamp)
+	s.Contains(err.Error(), "failed to create migration 'b': migration '20200102030405_a' already has version 20200102030405")
+	_, err = os.Stat(filepath.Join(s.directory, "migrations", "20200102030405_b.up.sql"))
+	s.True(os.IsNotExist(err))
+}
+
+func (s *CreateTests) TestNewMigrationFiles_error() {
+	_, err := NewMigrationFiles(s.directory, "create users", SchemeSequence)
+	s.Contains(err.Error(), "names must only contain letters, digits, underscores and dashes")
+	_, err = NewMigrationFiles(s.directory, "", SchemeSequence)
+	s.NotNil(err)
+	_, err = NewMigrationFiles(filepath.Join(s.directory, "migrations"), "a", "semver")
+	s.Contains(err.Error(), "unknown versioning scheme 'semver'")
+	_, err = NewMigrationFiles(filepath.Join(s.directory, "migrations"), "a b", SchemeSequence)
+	s.NotNil(err)
+	_, err = os.Stat(filepath.Join(s.directory, "migrations"))
+	s.True(os.IsNotExist(err))
+}
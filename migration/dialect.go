@@ -0,0 +1,63 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Dialect provides the database-specific details the Engine needs to
+// manage the migrations table, queries passed to and returned from a
+// Dialect use `?` placeholders which the Engine rewrites for the driver
+type Dialect interface {
+	// Driver returns the name of the driver the dialect is for (one of
+	// the db.Driver* constants)
+	Driver() string
+	// CreateTableQuery returns the DDL for creating the migrations table
+	// named :tableName at LatestSchemaVersion, it must not fail if the
+	// table exists
+	CreateTableQuery(tableName string) string
+	// CreateLogTableQuery returns the DDL for creating the log table named
+	// :tableName (see LogEntry), it must not fail if the table exists
+	CreateLogTableQuery(tableName string) string
+	// CreateMetaTableQuery returns the DDL for creating the metadata table
+	// named :tableName with the string columns name (its primary key) and
+	// value, it must not fail if the table exists
+	CreateMetaTableQuery(tableName string) string
+	// TableExistsQuery returns a query that selects the number of tables
+	// in the current schema named by its only placeholder
+	TableExistsQuery() string
+	// ColumnsQuery returns a query that selects the names of the columns of
+	// the table in the current schema named by its only placeholder
+	ColumnsQuery() string
+	// TimestampType returns the column type used for timestamps
+	TimestampType() string
+	// AddColumnQuery returns the DDL for adding the column named :column
+	// with the :definition parameter to the table named :tableName
+	AddColumnQuery(tableName, column, definition string) string
+	// Insert inserts a row with the :values parameter into the :columns of
+	// the table named :tableName and returns the id of the inserted row
+	Insert(ctx context.Context, connection Queryer, tableName string, columns []string, values []interface{}) (int64, error)
+	// Split splits a migration script into statements (or batches) that
+	// can be executed by the driver one after another, see SplitStatements
+	Split(script string) []string
+	// TransactionalDDL returns true if schema changes can be rolled back
+	// as part of a transaction, migrations and their tracking rows are
+	// then applied in a single transaction unless they opt out with
+	// NoTransaction or a `-- +migrate NoTransaction` header
+	TransactionalDDL() bool
+	// Lock acquires the database-level advisory lock named :name for the
+	// session of the :connection parameter, returning ErrLockTimeout if the
+	// lock could not be acquired within the :timeout parameter
+	Lock(ctx context.Context, connection *sql.Conn, name string, timeout time.Duration) error
+	// Unlock releases the advisory lock named :name held by the session of
+	// the :connection parameter
+	Unlock(ctx context.Context, connection *sql.Conn, name string) error
+}
+
+// Queryer is implemented by *sql.DB, *sql.Conn and *sql.Tx
+type Queryer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
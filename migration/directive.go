@@ -0,0 +1,48 @@
+package migration
+
+import "strings"
+
+const (
+	// DirectivePrefix starts a directive comment in a migration script
+	DirectivePrefix = "+migrate"
+	// DirectiveNoTransaction prevents a migration script from being
+	// executed inside a transaction
+	DirectiveNoTransaction = "NoTransaction"
+)
+
+// HasDirective returns true if the leading comments of the :script
+// parameter contain a `-- +migrate <directive>` line for the :directive
+// parameter, leading /* block */ comments (such as license headers) are
+// skipped but directives inside them are ignored
+func HasDirective(script, directive string) bool {
+	for remaining := script; ; {
+		remaining = strings.TrimSpace(remaining)
+		var line string
+		switch {
+		case strings.HasPrefix(remaining, "/*"):
+			end := strings.Index(remaining, "*/")
+			if end == -1 {
+				return false
+			}
+			remaining = remaining[end+2:]
+			continue
+		case strings.HasPrefix(remaining, "--"):
+			line = remaining
+			if end := strings.IndexByte(remaining, '\n'); end != -1 {
+				line, remaining = remaining[:end], remaining[end+1:]
+			} else {
+				remaining = ""
+			}
+		default:
+			return false
+		}
+		fields := strings.Fields(strings.TrimPrefix(line, "--"))
+		if len(fields) > 1 && fields[0] == DirectivePrefix {
+			for j := 1; j < len(fields); j++ {
+				if strings.EqualFold(fields[j], directive) {
+					return true
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package migration
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type DirectiveTests struct {
+	suite.Suite
+}
+
+func TestDirective(t *testing.T) {
+	suite.Run(t, &DirectiveTests{})
+}
+
+func (s *DirectiveTests) TestHasDirective() {
+	s.True(HasDirective("-- +migrate NoTransaction\nCREATE INDEX CONCURRENTLY a ON b (c);", DirectiveNoTransaction))
+	s.True(HasDirective("\n-- description\n--   +migrate   notransaction\nSELECT 1;", DirectiveNoTransaction))
+	s.False(HasDirective("SELECT 1;\n-- +migrate NoTransaction", DirectiveNoTransaction))
+	s.False(HasDirective("-- +migrate\nSELECT 1;", DirectiveNoTransaction))
+	s.False(HasDirective("-- migrate NoTransaction\nSELECT 1;", DirectiveNoTransaction))
+}
+
+func (s *DirectiveTests) TestHasDirective_blockComments() {
+	s.True(HasDirective("/*\n * Copyright\n */\n-- +migrate NoTransaction\nCREATE INDEX CONCURRENTLY a ON b (c);", DirectiveNoTransaction))
+	s.True(HasDirective("-- description\n/* header */ -- +migrate NoTransaction\nSELECT 1;", DirectiveNoTransaction))
+	s.False(HasDirective("/* header */ SELECT 1;\n-- +migrate NoTransaction", DirectiveNoTransaction))
+	s.False(HasDirective("/* -- +migrate NoTransaction */\nSELECT 1;", DirectiveNoTransaction))
+	s.False(HasDirective("/* unterminated\n-- +migrate NoTransaction", DirectiveNoTransaction))
+}
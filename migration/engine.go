@@ -0,0 +1,522 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/usvc/go-db"
+)
+
+// Engine applies, rolls back, resolves and validates migrations while
+// keeping their history in a migrations table, database-specific details
+// are delegated to its Dialect
+type Engine struct {
+	// Dialect provides the database-specific details of the connection
+	Dialect Dialect
+	// TableName is the name of the table used to track migrations
+	TableName string
+	// Connection is the database connection to migrate
+	Connection *sql.DB
+}
+
+// NewEngine returns an Engine which tracks migrations in the table named
+// :tableName using the :dialect parameter
+func NewEngine(dialect Dialect, tableName string, connection *sql.DB) *Engine {
+	return &Engine{
+		Dialect:    dialect,
+		TableName:  tableName,
+		Connection: connection,
+	}
+}
+
+// Init creates the migrations table and its companion tables if they do
+// not exist and upgrades them otherwise, see EnsureTable
+func (e *Engine) Init(ctx context.Context) error {
+	return e.EnsureTable(ctx)
+}
+
+// TableExists returns true if the migrations table exists
+func (e *Engine) TableExists(ctx context.Context) (bool, error) {
+	return e.tableExists(ctx, e.TableName)
+}
+
+// tableExists returns true if the table named :tableName exists
+func (e *Engine) tableExists(ctx context.Context, tableName string) (bool, error) {
+	var count int64
+	err := e.Connection.QueryRowContext(ctx, db.Rebind(e.Dialect.Driver(), e.Dialect.TableExistsQuery()), tableName).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("failed to query row: '%s'", err)
+	}
+	return count > 0, nil
+}
+
+// LockName returns the name of the advisory lock which guards the
+// migrations table
+func (e *Engine) LockName() string {
+	return e.TableName + "_lock"
+}
+
+// Lock acquires the advisory lock which guards the migrations table on a
+// dedicated connection so that concurrent processes do not migrate at the
+// same time, the returned function releases the lock
+func (e *Engine) Lock(ctx context.Context, timeout time.Duration) (func() error, error) {
+	connection, err := e.Connection.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get a connection for lock '%s': '%s'", e.LockName(), err)
+	}
+	if err := e.Dialect.Lock(ctx, connection, e.LockName(), timeout); err != nil {
+		connection.Close()
+		if err == ErrLockTimeout {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to acquire lock '%s': '%s'", e.LockName(), err)
+	}
+	return func() error {
+		defer connection.Close()
+		if err := e.Dialect.Unlock(context.Background(), connection, e.LockName()); err != nil {
+			return fmt.Errorf("failed to release lock '%s': '%s'", e.LockName(), err)
+		}
+		return nil
+	}, nil
+}
+
+// Apply executes the upward script of the :m parameter and records it in
+// the migrations table, NoErrAlreadyApplied is returned if the migration
+// has already been recorded. The row of a migration which has been rolled
+// back is reused when it is applied again.
+//
+// On dialects with transactional DDL the tracking row, the upward script
+// and the status update are executed in a single transaction so that a
+// failure leaves neither a partial schema nor a row stuck in
+// StatusApplying, the failure is then recorded outside of the rolled back
+// transaction. Migrations with NoTransaction set or a
+// `-- +migrate NoTransaction` header are executed statement by statement
+// as they are on dialects without transactional DDL (such as MySQL, where
+// DDL statements implicitly commit any open transaction)
+//
+// Every attempt is recorded in the log table (see History), a *LogError is
+// returned if the migration was applied but could not be logged
+func (e *Engine) Apply(ctx context.Context, m *Migration) error {
+	started := time.Now()
+	err := e.apply(ctx, m)
+	if err == NoErrAlreadyApplied {
+		return err
+	}
+	return e.log(OperationApply, m, started, err)
+}
+
+func (e *Engine) apply(ctx context.Context, m *Migration) error {
+	id, status, err := e.recorded(ctx, m)
+	if err != nil {
+		return fmt.Errorf("[apply:%s] failed to check if migration has already been applied: '%s'", m.Name, err)
+	} else if id != 0 && status != StatusRolledBack {
+		return NoErrAlreadyApplied
+	}
+	transactional := e.transactional(m, m.Up, m.UpFunc)
+	connection, finish, err := e.begin(ctx, transactional)
+	if err != nil {
+		return fmt.Errorf("[apply:%s] failed to begin transaction: '%s'", m.Name, err)
+	}
+	if err = e.record(ctx, connection, m, id, nil); err != nil {
+		finish(false)
+		return fmt.Errorf("[apply:%s] failed to insert migration entry into migration table '%s': '%s'", m.Name, e.TableName, err)
+	}
+	if err = e.execute(ctx, connection, m.Up, m.UpFunc); err != nil {
+		finish(false)
+		if setErrorErr := e.setError(ctx, m, err, transactional, id); setErrorErr != nil {
+			return fmt.Errorf("[apply:%s] failed to apply migration: '%s'", m.Name, setErrorErr)
+		}
+		return fmt.Errorf("[apply:%s] failed to apply migration: '%s'", m.Name, err)
+	}
+	_, err = connection.ExecContext(ctx, e.query("UPDATE %s SET applied_at = ?, status = ? WHERE id = ?"), time.Now(), StatusApplied, m.ID)
+	if err != nil {
+		finish(false)
+		return fmt.Errorf("[apply:%s] failed to indicate success for migration: '%s'", m.Name, err)
+	}
+	if err = finish(true); err != nil {
+		return fmt.Errorf("[apply:%s] failed to commit migration: '%s'", m.Name, err)
+	}
+	return nil
+}
+
+// Rollback executes the downward script of the :m parameter and marks its
+// row in the migrations table as StatusRolledBack, on dialects with
+// transactional DDL this happens in a single transaction unless the
+// migration opts out of it, and a failed transactional rollback leaves the
+// row applied. A *StateError is returned if the migration is not recorded
+// as StatusApplied or StatusBaselined. Every attempt is
+// recorded in the log table, a *LogError is returned if the migration was
+// rolled back but could not be logged
+func (e *Engine) Rollback(ctx context.Context, m *Migration) error {
+	started := time.Now()
+	return e.log(OperationRollback, m, started, e.rollback(ctx, m))
+}
+
+func (e *Engine) rollback(ctx context.Context, m *Migration) error {
+	id, status, err := e.recorded(ctx, m)
+	if err != nil {
+		return fmt.Errorf("[rollback:%s] failed to retrieve migration entry: '%s'", m.Name, err)
+	} else if id == 0 || !isApplied(status) {
+		return &StateError{Operation: "rollback", Name: m.Name, Status: status}
+	} else if m.kind() == KindGo && m.DownFunc == nil {
+		return fmt.Errorf("[rollback:%s] failed to rollback migration: migration has no downward function", m.Name)
+	}
+	transactional := e.transactional(m, m.Down, m.DownFunc)
+	connection, finish, err := e.begin(ctx, transactional)
+	if err != nil {
+		return fmt.Errorf("[rollback:%s] failed to begin transaction: '%s'", m.Name, err)
+	}
+	_, err = connection.ExecContext(ctx, e.query("UPDATE %s SET status = ?, started_at = ? WHERE id = ?"), StatusRollingBack, time.Now(), id)
+	if err != nil {
+		finish(false)
+		return fmt.Errorf("[rollback:%s] failed to update status for rollback migration: '%s'", m.Name, err)
+	}
+	if err = e.execute(ctx, connection, m.Down, m.DownFunc); err != nil {
+		finish(false)
+		if transactional {
+			// the row is back to its applied status with the schema, the
+			// failure is only recorded in the log table
+			return fmt.Errorf("[rollback:%s] failed to rollback migration: '%s'", m.Name, err)
+		}
+		_, err2 := e.Connection.ExecContext(ctx, e.query("UPDATE %s SET error = ? WHERE id = ?"), err.Error(), id)
+		if err2 != nil {
+			return fmt.Errorf("[rollback:%s] failed to set error column for failed rollback migration: '%s' > '%s'", m.Name, err, err2)
+		}
+		return fmt.Errorf("[rollback:%s] failed to rollback migration: '%s'", m.Name, err)
+	}
+	_, err = connection.ExecContext(ctx, e.query("UPDATE %s SET status = ?, rolled_back_at = ? WHERE id = ?"), StatusRolledBack, time.Now(), id)
+	if err != nil {
+		finish(false)
+		return fmt.Errorf("[rollback:%s] failed to indicate success for rollback migration: '%s'", m.Name, err)
+	}
+	if err = finish(true); err != nil {
+		return fmt.Errorf("[rollback:%s] failed to commit rollback migration: '%s'", m.Name, err)
+	}
+	return nil
+}
+
+// Resolve removes the :m parameter from the migrations table so that it
+// can be applied again after a failure, the log table keeps a record of
+// the failure and its resolution
+func (e *Engine) Resolve(ctx context.Context, m *Migration) error {
+	started := time.Now()
+	var err error
+	if deleteErr := e.delete(ctx, e.Connection, m); deleteErr != nil {
+		err = fmt.Errorf("[resolve:%s] failed to resolve migration error: '%s'", m.Name, deleteErr)
+	}
+	return e.log(OperationResolve, m, started, err)
+}
+
+// Validate verifies that the :m parameter has been applied successfully
+// and that its checksum matches the one recorded in the migrations table
+// (rows recorded before checksums were introduced are compared using the
+// canonical tokens of their scripts), NoErrDoesNotExist is returned if the
+// migration has not been recorded or has been rolled back and a
+// *StateError if it is still being applied or rolled back (or its process
+// died while doing so, see Runner.Repair)
+func (e *Engine) Validate(ctx context.Context, m *Migration) error {
+	remoteMigration, err := e.Load(ctx, m.Name)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return NoErrDoesNotExist
+		}
+		return fmt.Errorf("[validate:%s] failed to retrieve migration entry: %s", m.Name, err)
+	}
+	return e.compare(m, remoteMigration)
+}
+
+// compare validates the :m parameter against its recorded version, the
+// :remoteMigration parameter, as described by Validate
+func (e *Engine) compare(m *Migration, remoteMigration *Migration) error {
+	if remoteMigration.Status == StatusRolledBack {
+		return NoErrDoesNotExist
+	}
+	if remoteMigration.Error != nil && len(*remoteMigration.Error) > 0 {
+		return fmt.Errorf("[validate:%s] migration exists but has been recorded as failed: '%s'", m.Name, *remoteMigration.Error)
+	} else if isInProgress(remoteMigration.Status) {
+		return &StateError{Operation: "validate", Name: m.Name, Status: remoteMigration.Status}
+	}
+	checksum := e.recordedChecksum(remoteMigration)
+	if checksum == Checksum(e.Dialect.Driver(), m) {
+		return nil
+	} else if !e.sameScript(m.Up, remoteMigration.Up) {
+		return fmt.Errorf("[validate:%s] failed to reconcile upward migration query local and remote versions:\n%s\n--\n%s", m.Name, m.Up, remoteMigration.Up)
+	} else if !e.sameScript(m.Down, remoteMigration.Down) {
+		return fmt.Errorf("[validate:%s] failed to reconcile downward migration query local and remote versions:\n%s\n--\n%s", m.Name, m.Down, remoteMigration.Down)
+	}
+	return fmt.Errorf("[validate:%s] failed to reconcile local and remote checksums: '%s' != '%s'", m.Name, Checksum(e.Dialect.Driver(), m), checksum)
+}
+
+// Load retrieves the migration named :name from the migrations table,
+// sql.ErrNoRows is returned if it has not been recorded
+func (e *Engine) Load(ctx context.Context, name string) (*Migration, error) {
+	migration, err := scanMigration(e.Connection.QueryRowContext(ctx, e.query(
+		`SELECT `+migrationColumns+`
+			FROM %s
+				WHERE name = ?`,
+	), name))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to retrieve row from database for migration '%s': '%s'", name, err)
+	}
+	return migration, nil
+}
+
+// List retrieves every migration recorded in the migrations table in the
+// order they were recorded
+func (e *Engine) List(ctx context.Context) (Migrations, error) {
+	rows, err := e.Connection.QueryContext(ctx, e.query(`SELECT `+migrationColumns+` FROM %s ORDER BY id`))
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve rows from database: '%s'", err)
+	}
+	defer rows.Close()
+	var migrations Migrations
+	for rows.Next() {
+		migration, err := scanMigration(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row from database: '%s'", err)
+		}
+		migrations = append(migrations, migration)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to retrieve rows from database: '%s'", err)
+	}
+	return migrations, nil
+}
+
+// begin returns the connection that migration scripts should be executed
+// with (a transaction if the :transactional parameter is true) and a
+// function which commits or rolls back the transaction
+func (e *Engine) begin(ctx context.Context, transactional bool) (Queryer, func(commit bool) error, error) {
+	if !transactional {
+		return e.Connection, func(bool) error { return nil }, nil
+	}
+	tx, err := e.Connection.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tx, func(commit bool) error {
+		if commit {
+			return tx.Commit()
+		}
+		return tx.Rollback()
+	}, nil
+}
+
+// columns returns the lowercased names of the columns of the migrations
+// table
+func (e *Engine) columns(ctx context.Context) ([]string, error) {
+	rows, err := e.Connection.QueryContext(ctx, db.Rebind(e.Dialect.Driver(), e.Dialect.ColumnsQuery()), e.TableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var columns []string
+	for rows.Next() {
+		var column string
+		if err := rows.Scan(&column); err != nil {
+			return nil, err
+		}
+		columns = append(columns, strings.ToLower(column))
+	}
+	return columns, rows.Err()
+}
+
+func (e *Engine) delete(ctx context.Context, connection Queryer, m *Migration) error {
+	_, err := connection.ExecContext(ctx, e.query("DELETE FROM %s WHERE name = ?"), m.Name)
+	if err != nil {
+		return fmt.Errorf("failed to delete migration entry: '%s'", err)
+	}
+	return nil
+}
+
+// execute calls the :function parameter with the transaction of the
+// :connection parameter if it is not nil and executes the :script
+// parameter otherwise
+func (e *Engine) execute(ctx context.Context, connection Queryer, script string, function MigrationFunc) error {
+	if function == nil {
+		return e.execScript(ctx, connection, script)
+	}
+	tx, ok := connection.(*sql.Tx)
+	if !ok {
+		return fmt.Errorf("migration functions must be executed in a transaction")
+	}
+	return function(ctx, tx)
+}
+
+// execScript executes each statement of the :script parameter as split by
+// the dialect, returning a *scriptError if a statement fails
+func (e *Engine) execScript(ctx context.Context, connection Queryer, script string) error {
+	statements := e.Dialect.Split(script)
+	for i := 0; i < len(statements); i++ {
+		if _, err := connection.ExecContext(ctx, statements[i]); err != nil {
+			return &scriptError{statement: i + 1, statements: len(statements), err: err}
+		}
+	}
+	return nil
+}
+
+// recorded returns the id and status of the row of the :m parameter in
+// the migrations table, the id is 0 if the migration has not been recorded
+func (e *Engine) recorded(ctx context.Context, m *Migration) (int64, string, error) {
+	var id int64
+	var status string
+	err := e.Connection.QueryRowContext(ctx, e.query("SELECT id, status FROM %s WHERE name = ?"), m.Name).Scan(&id, &status)
+	if err == nil {
+		return id, status, nil
+	} else if err == sql.ErrNoRows {
+		return 0, "", nil
+	}
+	return 0, "", fmt.Errorf("failed to query row: '%s'", err)
+}
+
+// record inserts the row of the :m parameter into the migrations table
+// with StatusApplying and the :errorText parameter, the row with the id
+// :reuse is updated instead if it is not 0. Errors are not prefixed with
+// an operation so that callers can describe the operation which failed
+func (e *Engine) record(ctx context.Context, connection Queryer, m *Migration, reuse int64, errorText *string) error {
+	checksum := Checksum(e.Dialect.Driver(), m)
+	if reuse != 0 {
+		_, err := connection.ExecContext(
+			ctx,
+			e.query("UPDATE %s SET up = ?, down = ?, checksum = ?, status = ?, kind = ?, started_at = ?, error = ?, applied_at = NULL, rolled_back_at = NULL WHERE id = ?"),
+			m.Up, m.Down, checksum, StatusApplying, m.kind(), time.Now(), errorText, reuse,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to update row %v: '%s'", reuse, err)
+		}
+		m.ID = reuse
+		return nil
+	}
+	columns := []string{"name", "up", "down", "checksum", "status", "kind", "started_at"}
+	values := []interface{}{m.Name, m.Up, m.Down, checksum, StatusApplying, m.kind(), time.Now()}
+	if errorText != nil {
+		columns = append(columns, "error")
+		values = append(values, *errorText)
+	}
+	id, err := e.Dialect.Insert(ctx, connection, e.TableName, columns, values)
+	if err != nil {
+		return fmt.Errorf("failed to insert initial row: '%s'", err)
+	}
+	m.ID = id
+	return nil
+}
+
+// query formats the :format parameter with the migrations table name and
+// rewrites its placeholders for the driver
+func (e *Engine) query(format string) string {
+	return db.Rebind(e.Dialect.Driver(), fmt.Sprintf(format, e.TableName))
+}
+
+// recordedChecksum returns the checksum recorded for the :remote
+// parameter, computing it from its scripts if it was recorded before
+// checksums were introduced
+func (e *Engine) recordedChecksum(remote *Migration) string {
+	if remote.Checksum != nil && len(*remote.Checksum) > 0 {
+		return *remote.Checksum
+	}
+	return Checksum(e.Dialect.Driver(), remote)
+}
+
+// sameScript returns true if the :local and :remote parameters have the
+// same canonical tokens
+func (e *Engine) sameScript(local, remote string) bool {
+	localTokens := CanonicalTokens(e.Dialect.Driver(), local)
+	remoteTokens := CanonicalTokens(e.Dialect.Driver(), remote)
+	if len(localTokens) != len(remoteTokens) {
+		return false
+	}
+	for i := 0; i < len(localTokens); i++ {
+		if localTokens[i] != remoteTokens[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// setError records the :originalError parameter against the :m parameter,
+// if the :rolledBack parameter is true the tracking row was rolled back
+// along with the migration and is recorded again (reusing the row with the
+// id :reuse if it is not 0)
+func (e *Engine) setError(ctx context.Context, m *Migration, originalError error, rolledBack bool, reuse int64) error {
+	if rolledBack {
+		errorText := originalError.Error()
+		if recordError := e.record(ctx, e.Connection, m, reuse, &errorText); recordError != nil {
+			return fmt.Errorf("failed to record error '%s': '%s'", originalError, recordError)
+		}
+		return nil
+	}
+	_, updateError := e.Connection.ExecContext(ctx, e.query("UPDATE %s SET error = ? WHERE id = ?"), originalError.Error(), m.ID)
+	if updateError != nil {
+		return fmt.Errorf("failed to set error column to '%s': '%s'", originalError, updateError)
+	}
+	return nil
+}
+
+// transactional returns true if the :script parameter or the :function
+// parameter of the :m parameter should be executed inside a transaction,
+// functions are always executed inside a transaction
+func (e *Engine) transactional(m *Migration, script string, function MigrationFunc) bool {
+	if function != nil {
+		return true
+	}
+	return e.Dialect.TransactionalDDL() && !m.NoTransaction && !HasDirective(script, DirectiveNoTransaction)
+}
+
+// isInProgress returns true if the :status parameter is that of a
+// migration which is being applied or rolled back
+func isInProgress(status string) bool {
+	return status == StatusApplying || status == StatusRollingBack
+}
+
+// isApplied returns true if the :status parameter is that of a migration
+// which is reflected in the schema
+func isApplied(status string) bool {
+	return status == StatusApplied || status == StatusBaselined
+}
+
+// migrationColumns are the columns of the migrations table selected by
+// Load and List in the order expected by scanMigration
+const migrationColumns = `id, name, up, down, checksum, error, status, applied_at, created_at, rolled_back_at, kind, started_at`
+
+// scanMigration scans a row containing the migrationColumns
+func scanMigration(row interface{ Scan(...interface{}) error }) (*Migration, error) {
+	migration := Migration{}
+	if err := row.Scan(
+		&migration.ID,
+		&migration.Name,
+		&migration.Up,
+		&migration.Down,
+		&migration.Checksum,
+		&migration.Error,
+		&migration.Status,
+		&migration.AppliedAt,
+		&migration.CreatedAt,
+		&migration.RolledBackAt,
+		&migration.Kind,
+		&migration.StartedAt,
+	); err != nil {
+		return nil, err
+	}
+	return &migration, nil
+}
+
+// scriptError is returned when a statement of a migration script fails
+type scriptError struct {
+	statement  int
+	statements int
+	err        error
+}
+
+// Error implements the error interface
+func (e *scriptError) Error() string {
+	if e.statements > 1 {
+		return fmt.Sprintf("statement %v of %v: %s", e.statement, e.statements, e.err)
+	}
+	return e.err.Error()
+}
@@ -0,0 +1,388 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/suite"
+	"github.com/usvc/go-db"
+)
+
+// testDialect is a minimal Dialect for testing the Engine against sqlmock
+type testDialect struct {
+	transactional bool
+	driver        string
+}
+
+func (d testDialect) Driver() string {
+	if len(d.driver) > 0 {
+		return d.driver
+	}
+	return db.DriverPostgreSQL
+}
+
+func (testDialect) CreateTableQuery(tableName string) string {
+	return fmt.Sprintf("CREATE TABLE %s", tableName)
+}
+
+func (testDialect) CreateLogTableQuery(tableName string) string {
+	return fmt.Sprintf("CREATE TABLE %s", tableName)
+}
+
+func (testDialect) CreateMetaTableQuery(tableName string) string {
+	return fmt.Sprintf("CREATE TABLE %s", tableName)
+}
+
+func (testDialect) TableExistsQuery() string {
+	return "SELECT COUNT(*) FROM tables WHERE name = ?"
+}
+
+func (testDialect) ColumnsQuery() string {
+	return "SELECT name FROM columns WHERE table_name = ?"
+}
+
+func (testDialect) TimestampType() string { return "TIMESTAMPTZ" }
+
+func (testDialect) AddColumnQuery(tableName, column, definition string) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", tableName, column, definition)
+}
+
+func (testDialect) Insert(ctx context.Context, connection Queryer, tableName string, columns []string, values []interface{}) (int64, error) {
+	var id int64
+	err := connection.QueryRowContext(ctx, db.Rebind(db.DriverPostgreSQL, fmt.Sprintf(
+		"INSERT INTO %s (%s) VALUES (%s) RETURNING id", tableName, strings.Join(columns, ", "), Placeholders(len(values)),
+	)), values...).Scan(&id)
+	return id, err
+}
+
+func (testDialect) Split(script string) []string { return strings.Split(script, "\nGO\n") }
+
+func (d testDialect) TransactionalDDL() bool { return d.transactional }
+
+func (testDialect) Lock(ctx context.Context, connection *sql.Conn, name string, timeout time.Duration) error {
+	var acquired int
+	if err := connection.QueryRowContext(ctx, "SELECT LOCK($1, $2)", name, timeout.Seconds()).Scan(&acquired); err != nil {
+		return err
+	} else if acquired != 1 {
+		return ErrLockTimeout
+	}
+	return nil
+}
+
+func (testDialect) Unlock(ctx context.Context, connection *sql.Conn, name string) error {
+	_, err := connection.ExecContext(ctx, "SELECT UNLOCK($1)", name)
+	return err
+}
+
+// expectEnsureTable expects the migrations table and its companion tables
+// to be created if they do not exist and found at LatestSchemaVersion
+func expectEnsureTable(mock sqlmock.Sqlmock) {
+	mock.ExpectExec("CREATE TABLE migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE migrations_log").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE migrations_meta").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT value FROM migrations_meta WHERE name = \\$1").WithArgs("schema_version").
+		WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow(strconv.Itoa(LatestSchemaVersion)))
+}
+
+// expectCheckTable expects the migrations table to be looked up and, if it
+// exists, its schema version to be read as the :version parameter
+func expectCheckTable(mock sqlmock.Sqlmock, exists bool, version int) {
+	count := 0
+	if exists {
+		count = 1
+	}
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM tables").WithArgs("migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(count))
+	if !exists {
+		return
+	}
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM tables").WithArgs("migrations_meta").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery("SELECT value FROM migrations_meta").WithArgs("schema_version").
+		WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow(strconv.Itoa(version)))
+}
+
+// expectLog expects the :operation on the migration named :name to be
+// recorded in the log table with the :outcome parameter
+func expectLog(mock sqlmock.Sqlmock, name, operation, outcome string) {
+	mock.ExpectExec("INSERT INTO migrations_log \\(migration, operation, outcome, error, duration_ms, host, executed_by, tool_version\\)").
+		WithArgs(name, operation, outcome, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), Version).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+}
+
+type EngineTests struct {
+	suite.Suite
+}
+
+func TestEngine(t *testing.T) {
+	suite.Run(t, &EngineTests{})
+}
+
+func (s *EngineTests) TestApply() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	mock.ExpectQuery("SELECT id, status FROM migrations WHERE name = \\$1").WithArgs("a").WillReturnRows(sqlmock.NewRows([]string{"id", "status"}))
+	mock.ExpectQuery("INSERT INTO migrations \\(name, up, down, checksum, status, kind, started_at\\) VALUES \\(\\$1, \\$2, \\$3, \\$4, \\$5, \\$6, \\$7\\) RETURNING id").
+		WithArgs("a", "UP 1\nGO\nUP 2", "DOWN", Checksum(db.DriverPostgreSQL, New("a", "UP 1\nGO\nUP 2", "DOWN")), StatusApplying, KindSQL, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectExec("UP 1").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UP 2").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE migrations SET applied_at = \\$1, status = \\$2 WHERE id = \\$3").
+		WithArgs(sqlmock.AnyArg(), StatusApplied, 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectLog(mock, "a", OperationApply, OutcomeSucceeded)
+	engine := NewEngine(testDialect{}, "migrations", connection)
+	migration := New("a", "UP 1\nGO\nUP 2", "DOWN")
+	s.Nil(engine.Apply(context.Background(), migration))
+	s.Equal(int64(7), migration.ID)
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *EngineTests) TestApply_transactional() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	mock.ExpectQuery("SELECT id, status FROM migrations").WillReturnRows(sqlmock.NewRows([]string{"id", "status"}))
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO migrations").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec("UP 1").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UP 2").WillReturnError(fmt.Errorf("this is expected"))
+	mock.ExpectRollback()
+	mock.ExpectQuery("INSERT INTO migrations \\(name, up, down, checksum, status, kind, started_at, error\\) VALUES \\(\\$1, \\$2, \\$3, \\$4, \\$5, \\$6, \\$7, \\$8\\) RETURNING id").
+		WithArgs("a", "UP 1\nGO\nUP 2", "DOWN", sqlmock.AnyArg(), StatusApplying, KindSQL, sqlmock.AnyArg(), "statement 2 of 2: this is expected").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	expectLog(mock, "a", OperationApply, OutcomeFailed)
+	engine := NewEngine(testDialect{transactional: true}, "migrations", connection)
+	migration := New("a", "UP 1\nGO\nUP 2", "DOWN")
+	err = engine.Apply(context.Background(), migration)
+	s.Contains(err.Error(), "failed to apply migration")
+	s.Equal(int64(2), migration.ID)
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *EngineTests) TestApply_noTransaction() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	up := "-- +migrate NoTransaction\nCREATE INDEX CONCURRENTLY a ON b (c)"
+	mock.ExpectQuery("SELECT id, status FROM migrations").WillReturnRows(sqlmock.NewRows([]string{"id", "status"}))
+	mock.ExpectQuery("INSERT INTO migrations").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec("CREATE INDEX CONCURRENTLY").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE migrations SET applied_at").WillReturnResult(sqlmock.NewResult(0, 1))
+	expectLog(mock, "a", OperationApply, OutcomeSucceeded)
+	mock.ExpectQuery("SELECT id, status FROM migrations").WillReturnRows(sqlmock.NewRows([]string{"id", "status"}))
+	mock.ExpectQuery("INSERT INTO migrations").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	mock.ExpectExec("UP").WillReturnError(fmt.Errorf("this is expected"))
+	mock.ExpectExec("UPDATE migrations SET error = \\$1 WHERE id = \\$2").WithArgs("this is expected", 2).WillReturnResult(sqlmock.NewResult(0, 1))
+	expectLog(mock, "b", OperationApply, OutcomeFailed)
+	engine := NewEngine(testDialect{transactional: true}, "migrations", connection)
+	s.Nil(engine.Apply(context.Background(), New("a", up, "DOWN")))
+	migration := New("b", "UP", "DOWN")
+	migration.NoTransaction = true
+	err = engine.Apply(context.Background(), migration)
+	s.Contains(err.Error(), "failed to apply migration")
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *EngineTests) TestApply_func() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	mock.ExpectQuery("SELECT id, status FROM migrations").WithArgs("a").WillReturnRows(sqlmock.NewRows([]string{"id", "status"}))
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO migrations").WithArgs("a", "", "", sqlmock.AnyArg(), StatusApplying, KindGo, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec("UPDATE users SET backfilled").WillReturnResult(sqlmock.NewResult(0, 10))
+	mock.ExpectExec("UPDATE migrations SET applied_at").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectLog(mock, "a", OperationApply, OutcomeSucceeded)
+	mock.ExpectQuery("SELECT id, status FROM migrations").WithArgs("b").WillReturnRows(sqlmock.NewRows([]string{"id", "status"}))
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO migrations").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	mock.ExpectRollback()
+	mock.ExpectQuery("INSERT INTO migrations \\(name, up, down, checksum, status, kind, started_at, error\\)").
+		WithArgs("b", "", "", sqlmock.AnyArg(), StatusApplying, KindGo, sqlmock.AnyArg(), "this is expected").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	expectLog(mock, "b", OperationApply, OutcomeFailed)
+	engine := NewEngine(testDialect{}, "migrations", connection)
+	s.Nil(engine.Apply(context.Background(), NewFunc("a", func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, "UPDATE users SET backfilled = TRUE")
+		return err
+	}, nil)))
+	err = engine.Apply(context.Background(), NewFunc("b", func(ctx context.Context, tx *sql.Tx) error {
+		return fmt.Errorf("this is expected")
+	}, nil))
+	s.Contains(err.Error(), "failed to apply migration: 'this is expected'")
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *EngineTests) TestRollback_func() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	mock.ExpectQuery("SELECT id, status FROM migrations").WithArgs("a").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status"}).AddRow(3, StatusApplied))
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE migrations SET status = \\$1, started_at = \\$2 WHERE id = \\$3").WithArgs(StatusRollingBack, sqlmock.AnyArg(), 3).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE users SET backfilled = FALSE").WillReturnResult(sqlmock.NewResult(0, 10))
+	mock.ExpectExec("UPDATE migrations SET status = \\$1, rolled_back_at").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectLog(mock, "a", OperationRollback, OutcomeSucceeded)
+	mock.ExpectQuery("SELECT id, status FROM migrations").WithArgs("b").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status"}).AddRow(4, StatusApplied))
+	expectLog(mock, "b", OperationRollback, OutcomeFailed)
+	engine := NewEngine(testDialect{}, "migrations", connection)
+	s.Nil(engine.Rollback(context.Background(), NewFunc("a", nil, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, "UPDATE users SET backfilled = FALSE")
+		return err
+	})))
+	remoteMigration := New("b", "", "")
+	remoteMigration.Kind = KindGo
+	err = engine.Rollback(context.Background(), remoteMigration)
+	s.Contains(err.Error(), "migration has no downward function")
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *EngineTests) TestRollback() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	mock.ExpectQuery("SELECT id, status FROM migrations WHERE name = \\$1").WithArgs("a").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status"}).AddRow(3, StatusApplied))
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE migrations SET status = \\$1, started_at = \\$2 WHERE id = \\$3").WithArgs(StatusRollingBack, sqlmock.AnyArg(), 3).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DOWN").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE migrations SET status = \\$1, rolled_back_at = \\$2 WHERE id = \\$3").
+		WithArgs(StatusRolledBack, sqlmock.AnyArg(), 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectLog(mock, "a", OperationRollback, OutcomeSucceeded)
+	engine := NewEngine(testDialect{transactional: true}, "migrations", connection)
+	s.Nil(engine.Rollback(context.Background(), New("a", "UP", "DOWN")))
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *EngineTests) TestRollback_transactional() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	mock.ExpectQuery("SELECT id, status FROM migrations WHERE name = \\$1").WithArgs("a").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status"}).AddRow(3, StatusApplied))
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE migrations SET status = \\$1, started_at = \\$2 WHERE id = \\$3").WithArgs(StatusRollingBack, sqlmock.AnyArg(), 3).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DOWN").WillReturnError(fmt.Errorf("this is expected"))
+	mock.ExpectRollback()
+	expectLog(mock, "a", OperationRollback, OutcomeFailed)
+	columns := []string{"id", "name", "up", "down", "checksum", "error", "status", "applied_at", "created_at", "rolled_back_at", "kind", "started_at"}
+	mock.ExpectQuery("SELECT (.+) FROM migrations WHERE name = \\$1").WithArgs("a").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(3, "a", "UP", "DOWN", nil, nil, StatusApplied, time.Now(), time.Now(), nil, KindSQL, nil))
+	engine := NewEngine(testDialect{transactional: true}, "migrations", connection)
+	err = engine.Rollback(context.Background(), New("a", "UP", "DOWN"))
+	s.Contains(err.Error(), "failed to rollback migration: 'this is expected'")
+	remoteMigration, err := engine.Load(context.Background(), "a")
+	s.Nil(err)
+	s.Equal(StatusApplied, remoteMigration.Status)
+	s.Nil(remoteMigration.Error)
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *EngineTests) TestRollback_error_state() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	mock.ExpectQuery("SELECT id, status FROM migrations").WithArgs("a").WillReturnRows(sqlmock.NewRows([]string{"id", "status"}))
+	expectLog(mock, "a", OperationRollback, OutcomeFailed)
+	mock.ExpectQuery("SELECT id, status FROM migrations").WithArgs("a").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status"}).AddRow(3, StatusRolledBack))
+	expectLog(mock, "a", OperationRollback, OutcomeFailed)
+	engine := NewEngine(testDialect{transactional: true}, "migrations", connection)
+	err = engine.Rollback(context.Background(), New("a", "UP", "DOWN"))
+	s.Equal(&StateError{Operation: "rollback", Name: "a"}, err)
+	s.Contains(err.Error(), "migration has not been recorded")
+	err = engine.Rollback(context.Background(), New("a", "UP", "DOWN"))
+	s.Equal(&StateError{Operation: "rollback", Name: "a", Status: StatusRolledBack}, err)
+	s.Contains(err.Error(), "cannot be processed in status 'rolled back'")
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *EngineTests) TestApply_rolledBack() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	mock.ExpectQuery("SELECT id, status FROM migrations").WithArgs("a").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status"}).AddRow(3, StatusRolledBack))
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE migrations SET up = \\$1, down = \\$2, checksum = \\$3, status = \\$4, kind = \\$5, started_at = \\$6, error = \\$7, applied_at = NULL, rolled_back_at = NULL WHERE id = \\$8").
+		WithArgs("UP", "DOWN", sqlmock.AnyArg(), StatusApplying, KindSQL, sqlmock.AnyArg(), nil, 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UP").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE migrations SET applied_at = \\$1, status = \\$2 WHERE id = \\$3").
+		WithArgs(sqlmock.AnyArg(), StatusApplied, 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectLog(mock, "a", OperationApply, OutcomeSucceeded)
+	engine := NewEngine(testDialect{transactional: true}, "migrations", connection)
+	migration := New("a", "UP", "DOWN")
+	s.Nil(engine.Apply(context.Background(), migration))
+	s.Equal(int64(3), migration.ID)
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *EngineTests) TestValidate() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	columns := []string{"id", "name", "up", "down", "checksum", "error", "status", "applied_at", "created_at", "rolled_back_at", "kind", "started_at"}
+	mock.ExpectQuery("SELECT (.+) FROM migrations\\s+WHERE name = \\$1").WithArgs("a").WillReturnRows(sqlmock.NewRows(columns))
+	mock.ExpectQuery("SELECT (.+) FROM migrations").WithArgs("a").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "a", "UP", "DOWN", nil, "this is expected", StatusApplying, nil, nil, nil, KindSQL, nil))
+	engine := NewEngine(testDialect{}, "migrations", connection)
+	s.Equal(NoErrDoesNotExist, engine.Validate(context.Background(), New("a", "UP", "DOWN")))
+	err = engine.Validate(context.Background(), New("a", "UP", "DOWN"))
+	s.Contains(err.Error(), "recorded as failed")
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *EngineTests) TestValidate_checksum() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	columns := []string{"id", "name", "up", "down", "checksum", "error", "status", "applied_at", "created_at", "rolled_back_at", "kind", "started_at"}
+	checksum := Checksum(db.DriverPostgreSQL, New("a", "UP", "DOWN"))
+	mock.ExpectQuery("SELECT (.+) FROM migrations").WithArgs("a").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "a", "UP", "DOWN", checksum, nil, StatusApplied, nil, nil, nil, KindSQL, nil))
+	mock.ExpectQuery("SELECT (.+) FROM migrations").WithArgs("a").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "a", "UP", "DOWN", checksum, nil, StatusApplied, nil, nil, nil, KindSQL, nil))
+	mock.ExpectQuery("SELECT (.+) FROM migrations").WithArgs("a").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "a", "UP", "DOWN", "stale", nil, StatusApplied, nil, nil, nil, KindSQL, nil))
+	engine := NewEngine(testDialect{}, "migrations", connection)
+	s.Nil(engine.Validate(context.Background(), New("a", "-- reformatted\nUP\n", "DOWN")))
+	err = engine.Validate(context.Background(), New("a", "UP", "DOWN 2"))
+	s.Contains(err.Error(), "failed to reconcile downward migration query")
+	err = engine.Validate(context.Background(), New("a", "UP", "DOWN"))
+	s.Contains(err.Error(), "failed to reconcile local and remote checksums")
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *EngineTests) TestLock() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	mock.ExpectQuery("SELECT LOCK").WithArgs("migrations_lock", float64(5)).WillReturnRows(sqlmock.NewRows([]string{"lock"}).AddRow(1))
+	mock.ExpectExec("SELECT UNLOCK").WithArgs("migrations_lock").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT LOCK").WithArgs("migrations_lock", float64(5)).WillReturnRows(sqlmock.NewRows([]string{"lock"}).AddRow(0))
+	engine := NewEngine(testDialect{}, "migrations", connection)
+	unlock, err := engine.Lock(context.Background(), 5*time.Second)
+	s.Nil(err)
+	s.Nil(unlock())
+	_, err = engine.Lock(context.Background(), 5*time.Second)
+	s.Equal(ErrLockTimeout, err)
+	s.Nil(mock.ExpectationsWereMet())
+}
@@ -0,0 +1,41 @@
+//go:build go1.16
+// +build go1.16
+
+package migration
+
+import (
+	"io/fs"
+	"os"
+)
+
+// FSSource is a Source which reads migration files from a fs.FS such as
+// an embed.FS
+type FSSource struct {
+	FS fs.FS
+}
+
+// ReadDir implements Source
+func (s FSSource) ReadDir(dir string) ([]os.FileInfo, error) {
+	entries, err := fs.ReadDir(s.FS, dir)
+	if err != nil {
+		return nil, err
+	}
+	fileInfos := make([]os.FileInfo, 0, len(entries))
+	for i := 0; i < len(entries); i++ {
+		fileInfo, err := entries[i].Info()
+		if err != nil {
+			return nil, err
+		}
+		fileInfos = append(fileInfos, fileInfo)
+	}
+	return fileInfos, nil
+}
+
+// ReadFile implements Source
+func (s FSSource) ReadFile(name string) ([]byte, error) { return fs.ReadFile(s.FS, name) }
+
+// NewFromFS loads the migrations in the directory :dir of the :fsys
+// parameter, allowing migrations to be embedded with `//go:embed`
+func NewFromFS(fsys fs.FS, dir string) (Migrations, error) {
+	return NewFromSource(FSSource{FS: fsys}, dir)
+}
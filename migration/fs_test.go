@@ -0,0 +1,19 @@
+//go:build go1.16
+// +build go1.16
+
+package migration
+
+import (
+	"testing/fstest"
+)
+
+func (s *SourceTests) TestNewFromFS() {
+	migrations, err := NewFromFS(fstest.MapFS{
+		"migrations/1_a.up.sql":   {Data: []byte("CREATE TABLE a (id INTEGER);")},
+		"migrations/1_a.down.sql": {Data: []byte("DROP TABLE a;")},
+	}, "migrations")
+	s.Nil(err)
+	s.Len(migrations, 1)
+	s.Equal("1_a", migrations[0].Name)
+	s.Equal("DROP TABLE a;", migrations[0].Down)
+}
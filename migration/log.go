@@ -0,0 +1,166 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/user"
+	"time"
+)
+
+// Version is the version of the migration tooling recorded in the log, it
+// can be set at build time with
+// -ldflags "-X github.com/usvc/go-db/migration.Version=<version>"
+var Version = "dev"
+
+const (
+	// OperationApply is logged when a migration is applied
+	OperationApply = "apply"
+	// OperationRollback is logged when a migration is rolled back
+	OperationRollback = "rollback"
+	// OperationResolve is logged when a failed migration is resolved
+	OperationResolve = "resolve"
+	// OperationBaseline is logged when a migration is baselined
+	OperationBaseline = "baseline"
+	// OperationRepair is logged when a stale or failed migration is
+	// repaired, see Runner.Repair
+	OperationRepair = "repair"
+
+	// OutcomeSucceeded is logged when an operation succeeds
+	OutcomeSucceeded = "succeeded"
+	// OutcomeFailed is logged when an operation fails
+	OutcomeFailed = "failed"
+)
+
+// LogEntry is a row of the append-only log of operations performed on
+// migrations
+type LogEntry struct {
+	// ID is the database-assigned ID of the entry
+	ID int64 `json:"id" yaml:"id"`
+	// Migration is the name of the migration the operation was performed on
+	Migration string `json:"migration" yaml:"migration"`
+	// Operation is one of the Operation* constants
+	Operation string `json:"operation" yaml:"operation"`
+	// Outcome is one of the Outcome* constants
+	Outcome string `json:"outcome" yaml:"outcome"`
+	// Error contains the error of a failed operation
+	Error *string `json:"error" yaml:"error"`
+	// Duration is the time the operation took
+	Duration time.Duration `json:"duration" yaml:"duration"`
+	// Host is the hostname of the machine which performed the operation
+	Host string `json:"host" yaml:"host"`
+	// ExecutedBy is the operating system user which performed the operation
+	ExecutedBy string `json:"executed_by" yaml:"executed_by"`
+	// ToolVersion is the Version of the tooling which performed the operation
+	ToolVersion string `json:"tool_version" yaml:"tool_version"`
+	// CreatedAt is the time the entry was recorded
+	CreatedAt *time.Time `json:"created_at" yaml:"created_at"`
+}
+
+// LogError is returned when an operation on a migration succeeded but could
+// not be recorded in the log table. The operation has been committed and
+// must not be retried, runners add it to Report.Warnings instead of
+// treating the migration as failed
+type LogError struct {
+	// Operation is one of the Operation* constants
+	Operation string
+	// Name is the name of the migration
+	Name string
+	// TableName is the name of the log table
+	TableName string
+	// Err is the error returned by the database while recording the entry
+	Err error
+}
+
+// Error implements the error interface
+func (e *LogError) Error() string {
+	return fmt.Sprintf("[%s:%s] failed to record operation in log table '%s': '%s'", e.Operation, e.Name, e.TableName, e.Err)
+}
+
+// isLogError returns true if the :err parameter is a *LogError
+func isLogError(err error) bool {
+	_, ok := err.(*LogError)
+	return ok
+}
+
+// LogTableName returns the name of the table which logs every operation
+// performed on migrations
+func (e *Engine) LogTableName() string {
+	return e.TableName + "_log"
+}
+
+// History returns the entries of the log in the order they were recorded
+func (e *Engine) History(ctx context.Context) ([]LogEntry, error) {
+	rows, err := e.Connection.QueryContext(ctx, e.query(
+		`SELECT id, migration, operation, outcome, error, duration_ms, host, executed_by, tool_version, created_at
+			FROM %s_log
+				ORDER BY id`,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve rows from log table '%s': '%s'", e.LogTableName(), err)
+	}
+	defer rows.Close()
+	var entries []LogEntry
+	for rows.Next() {
+		var entry LogEntry
+		var durationMilliseconds int64
+		if err := rows.Scan(
+			&entry.ID,
+			&entry.Migration,
+			&entry.Operation,
+			&entry.Outcome,
+			&entry.Error,
+			&durationMilliseconds,
+			&entry.Host,
+			&entry.ExecutedBy,
+			&entry.ToolVersion,
+			&entry.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan row from log table '%s': '%s'", e.LogTableName(), err)
+		}
+		entry.Duration = time.Duration(durationMilliseconds) * time.Millisecond
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to retrieve rows from log table '%s': '%s'", e.LogTableName(), err)
+	}
+	return entries, nil
+}
+
+// log records the :operation performed on the :m parameter which started
+// at the :started parameter and resulted in the :result parameter, which
+// is returned unless it is nil and the entry could not be recorded, in
+// which case a *LogError is returned. The entry is recorded even if the
+// context of the operation has been cancelled
+func (e *Engine) log(operation string, m *Migration, started time.Time, result error) error {
+	outcome := OutcomeSucceeded
+	var errorText *string
+	if result != nil {
+		outcome = OutcomeFailed
+		text := result.Error()
+		errorText = &text
+	}
+	host, executedBy := executor()
+	_, err := e.Connection.ExecContext(
+		context.Background(),
+		e.query("INSERT INTO %s_log (migration, operation, outcome, error, duration_ms, host, executed_by, tool_version) VALUES (?, ?, ?, ?, ?, ?, ?, ?)"),
+		m.Name, operation, outcome, errorText, int64(time.Since(started)/time.Millisecond), host, executedBy, Version,
+	)
+	if err != nil && result == nil {
+		return &LogError{Operation: operation, Name: m.Name, TableName: e.LogTableName(), Err: err}
+	}
+	return result
+}
+
+// executor returns the hostname and user of the current process
+func executor() (string, string) {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	username := os.Getenv("USER")
+	if currentUser, err := user.Current(); err == nil {
+		username = currentUser.Username
+	}
+	return host, username
+}
@@ -0,0 +1,71 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/suite"
+)
+
+type LogTests struct {
+	suite.Suite
+}
+
+func TestLog(t *testing.T) {
+	suite.Run(t, &LogTests{})
+}
+
+func (s *LogTests) TestHistory() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	createdAt := time.Now()
+	columns := []string{"id", "migration", "operation", "outcome", "error", "duration_ms", "host", "executed_by", "tool_version", "created_at"}
+	mock.ExpectQuery("SELECT (.+) FROM migrations_log\\s+ORDER BY id").WillReturnRows(sqlmock.NewRows(columns).
+		AddRow(1, "a", OperationApply, OutcomeFailed, "this is expected", 1500, "host", "user", "dev", createdAt).
+		AddRow(2, "a", OperationResolve, OutcomeSucceeded, nil, 2, "host", "user", "dev", createdAt))
+	entries, err := NewEngine(testDialect{}, "migrations", connection).History(context.Background())
+	s.Nil(err)
+	s.Len(entries, 2)
+	s.Equal(OperationApply, entries[0].Operation)
+	s.Equal(OutcomeFailed, entries[0].Outcome)
+	s.Equal("this is expected", *entries[0].Error)
+	s.Equal(1500*time.Millisecond, entries[0].Duration)
+	s.Equal(OperationResolve, entries[1].Operation)
+	s.Nil(entries[1].Error)
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *LogTests) TestLog() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	mock.ExpectExec("INSERT INTO migrations_log").
+		WithArgs("a", OperationApply, OutcomeFailed, "this is expected", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), Version).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO migrations_log").WillReturnError(fmt.Errorf("log failure"))
+	mock.ExpectExec("INSERT INTO migrations_log").WillReturnError(fmt.Errorf("log failure"))
+	engine := NewEngine(testDialect{}, "migrations", connection)
+	migration := New("a", "UP", "DOWN")
+	result := fmt.Errorf("this is expected")
+	s.Equal(result, engine.log(OperationApply, migration, time.Now(), result))
+	s.Equal(result, engine.log(OperationApply, migration, time.Now(), result))
+	err = engine.log(OperationRollback, migration, time.Now(), nil)
+	s.IsType(&LogError{}, err)
+	s.Contains(err.Error(), "[rollback:a] failed to record operation in log table 'migrations_log': 'log failure'")
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *LogTests) TestApply_alreadyApplied() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	mock.ExpectQuery("SELECT id, status FROM migrations").WithArgs("a").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status"}).AddRow(1, StatusApplied))
+	engine := NewEngine(testDialect{}, "migrations", connection)
+	s.Equal(NoErrAlreadyApplied, engine.Apply(context.Background(), New("a", "UP", "DOWN")))
+	s.Nil(mock.ExpectationsWereMet())
+}
@@ -1,6 +1,11 @@
 package migration
 
-import "database/sql"
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"time"
+)
 
 type Step interface {
 	Apply(string, *sql.DB) error
@@ -8,3 +13,115 @@ type Step interface {
 	Resolve(string, *sql.DB) error
 	Validate(string, *sql.DB) error
 }
+
+// MigrationFunc is the upward or downward function of a migration written
+// in Go, it is executed in a transaction which is committed along with the
+// migrations table if it returns nil
+type MigrationFunc func(ctx context.Context, tx *sql.Tx) error
+
+// Migration is a driver-agnostic schema migration which is applied and
+// rolled back using an Engine
+type Migration struct {
+	// ID will contain the database-assigned auto-incremented ID of the migration
+	ID int64 `json:"id" yaml:"id"`
+	// Name contains the name of the migration derived from the migration file name
+	Name string `json:"name" yaml:"name"`
+	// Up contains a SQL command that should result in a up-version shift of the database schema
+	Up string `json:"up" yaml:"up"`
+	// Down contains a SQL command that should result in a down-version shift of the database schema
+	Down string `json:"down" yaml:"down"`
+	// Error contains any error that happened
+	Error *string `json:"error" yaml:"error"`
+	// Checksum contains the checksum of the migration recorded in the
+	// migrations table, see Checksum
+	Checksum *string `json:"checksum" yaml:"checksum"`
+	// Status contains the status of the migration
+	Status string `json:"status" yaml:"status"`
+	// AppliedAt holds the timestamp when the migration was successfully applied to the database
+	AppliedAt *time.Time `json:"applied_at" yaml:"applied_at"`
+	// CreatedAt holds the timestamp when the migration was initialised in the database
+	CreatedAt *time.Time `json:"created_at" yaml:"created_at"`
+	// RolledBackAt holds the timestamp when the migration was last rolled back
+	RolledBackAt *time.Time `json:"rolled_back_at" yaml:"rolled_back_at"`
+	// StartedAt holds the timestamp when the migration last started being
+	// applied or rolled back
+	StartedAt *time.Time `json:"started_at" yaml:"started_at"`
+	// NoTransaction prevents the migration from being executed inside a
+	// transaction (eg. for `CREATE INDEX CONCURRENTLY` on PostgreSQL), this
+	// is also enabled by a `-- +migrate NoTransaction` header in its scripts
+	NoTransaction bool `json:"no_transaction" yaml:"no_transaction"`
+	// Path contains the path of the migration files without their extension
+	// if the migration was loaded from a Source
+	Path string `json:"path,omitempty" yaml:"path,omitempty"`
+	// Kind contains one of the Kind* constants, it is recorded in the
+	// migrations table and set by NewFunc
+	Kind string `json:"kind" yaml:"kind"`
+	// UpFunc is executed instead of Up if it is not nil, see NewFunc
+	UpFunc MigrationFunc `json:"-" yaml:"-"`
+	// DownFunc is executed instead of Down if it is not nil, see NewFunc
+	DownFunc MigrationFunc `json:"-" yaml:"-"`
+}
+
+func New(name, up, down string) *Migration {
+	return &Migration{
+		Name: name,
+		Up:   up,
+		Down: down,
+	}
+}
+
+// NewFunc returns a migration named :name which executes the :up and :down
+// functions (:down may be nil if the migration cannot be rolled back)
+// instead of scripts. Function migrations are ordered together with
+// scripted migrations by their names and are always executed in a
+// transaction, even on dialects without transactional DDL. Their
+// checksums cannot detect changes to the functions
+func NewFunc(name string, up, down MigrationFunc) *Migration {
+	return &Migration{
+		Name:     name,
+		Kind:     KindGo,
+		UpFunc:   up,
+		DownFunc: down,
+	}
+}
+
+// kind returns the kind of the migration, migrations with functions are
+// KindGo and migrations without a Kind are KindSQL
+func (m *Migration) kind() string {
+	if m.UpFunc != nil || m.DownFunc != nil || m.Kind == KindGo {
+		return KindGo
+	}
+	return KindSQL
+}
+
+// Version returns the numeric version prefix of the migration name (eg. 10
+// for "10_add_users" or 20200102150405 for "20200102150405-add-users") and
+// false if the name does not have one
+func (m *Migration) Version() (int64, bool) {
+	end := 0
+	for end < len(m.Name) && m.Name[end] >= '0' && m.Name[end] <= '9' {
+		end++
+	}
+	if end == 0 || (end < len(m.Name) && m.Name[end] != '_' && m.Name[end] != '-' && m.Name[end] != '.') {
+		return 0, false
+	}
+	version, err := strconv.ParseInt(m.Name[:end], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return version, true
+}
+
+// Before returns true if the migration should be applied before the :other
+// parameter, versioned migrations are ordered numerically and before
+// unversioned ones which are ordered by name
+func (m *Migration) Before(other *Migration) bool {
+	version, versioned := m.Version()
+	otherVersion, otherVersioned := other.Version()
+	if versioned && otherVersioned && version != otherVersion {
+		return version < otherVersion
+	} else if versioned != otherVersioned {
+		return versioned
+	}
+	return m.Name < other.Name
+}
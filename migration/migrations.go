@@ -0,0 +1,60 @@
+package migration
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	MigrationExtension = ".sql"
+)
+
+type Migrations []*Migration
+
+// Len implements the sort.Interface
+func (m Migrations) Len() int { return len(m) }
+
+// Swap implements the sort.Interface
+func (m Migrations) Swap(i, j int) { m[i], m[j] = m[j], m[i] }
+
+// Less implements the sort.Interface using Migration.Before
+func (m Migrations) Less(i, j int) bool {
+	return m[i].Before(m[j])
+}
+
+// Validate returns an error describing migrations which share a name or a
+// version, either of which makes the order of migrations ambiguous
+func (m Migrations) Validate() error {
+	var errs []string
+	names := map[string]*Migration{}
+	versions := map[int64]*Migration{}
+	for i := 0; i < len(m); i++ {
+		if other, ok := names[m[i].Name]; ok {
+			errs = append(errs, fmt.Sprintf("duplicate migration name '%s' found at %s and %s", m[i].Name, describePath(other), describePath(m[i])))
+			continue
+		}
+		names[m[i].Name] = m[i]
+		version, versioned := m[i].Version()
+		if !versioned {
+			continue
+		}
+		if other, ok := versions[version]; ok {
+			errs = append(errs, fmt.Sprintf("duplicate migration version %v used by '%s' at %s and '%s' at %s, renumber one of them", version, other.Name, describePath(other), m[i].Name, describePath(m[i])))
+			continue
+		}
+		versions[version] = m[i]
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid migrations: %s", strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// describePath returns the path of the :migration parameter for use in
+// error messages
+func describePath(migration *Migration) string {
+	if len(migration.Path) > 0 {
+		return fmt.Sprintf("'%s'", migration.Path)
+	}
+	return "(no path)"
+}
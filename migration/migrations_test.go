@@ -0,0 +1,54 @@
+package migration
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type MigrationsTests struct {
+	suite.Suite
+}
+
+func TestMigrations(t *testing.T) {
+	suite.Run(t, &MigrationsTests{})
+}
+
+func (s *MigrationsTests) TestSort() {
+	migrations := Migrations{
+		New("b", "", ""),
+		New("10_x", "", ""),
+		New("20200102150405_y", "", ""),
+		New("9_x", "", ""),
+		New("a", "", ""),
+		New("9-a", "", ""),
+	}
+	sort.Sort(migrations)
+	var names []string
+	for i := 0; i < len(migrations); i++ {
+		names = append(names, migrations[i].Name)
+	}
+	s.Equal([]string{"9-a", "9_x", "10_x", "20200102150405_y", "a", "b"}, names)
+}
+
+func (s *MigrationsTests) TestValidate() {
+	s.Nil(Migrations{New("1_a", "", ""), New("2_a", "", ""), New("a", "", "")}.Validate())
+	duplicateName := Migrations{New("1_a", "", ""), New("1_a", "", "")}
+	duplicateName[1].Path = "users/1_a"
+	s.Contains(duplicateName.Validate().Error(), "duplicate migration name '1_a' found at (no path) and 'users/1_a'")
+	err := Migrations{New("2_a", "", ""), New("002_b", "", "")}.Validate()
+	s.Contains(err.Error(), "duplicate migration version 2 used by '2_a'")
+}
+
+func (s *MigrationsTests) TestVersion() {
+	for name, expected := range map[string]int64{"10_x": 10, "007-x": 7, "20200102150405": 20200102150405, "3.x": 3} {
+		version, ok := New(name, "", "").Version()
+		s.True(ok, name)
+		s.Equal(expected, version, name)
+	}
+	for _, name := range []string{"x_10", "10x", "", "99999999999999999999_x"} {
+		_, ok := New(name, "", "").Version()
+		s.False(ok, name)
+	}
+}
@@ -0,0 +1,136 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/usvc/go-db"
+	"github.com/usvc/go-db/migration"
+)
+
+// Dialect implements migration.Dialect for Microsoft SQL Server, scripts
+// are split into batches on GO separators and migrations are run in
+// transactions
+type Dialect struct{}
+
+// Driver implements migration.Dialect
+func (Dialect) Driver() string { return db.DriverMSSQL }
+
+// CreateTableQuery implements migration.Dialect, SQL Server does not
+// accept IF NOT EXISTS in CREATE TABLE so the table is looked up instead
+func (Dialect) CreateTableQuery(tableName string) string {
+	return fmt.Sprintf(`
+		IF OBJECT_ID(N'%[1]s', N'U') IS NULL CREATE TABLE %[1]s (
+			id BIGINT IDENTITY(1,1) PRIMARY KEY,
+			name NVARCHAR(450) UNIQUE NOT NULL,
+			up NVARCHAR(MAX) NOT NULL,
+			down NVARCHAR(MAX) NOT NULL,
+			checksum VARCHAR(64),
+			error NVARCHAR(MAX),
+			status NVARCHAR(16) NOT NULL,
+			applied_at DATETIMEOFFSET,
+			rolled_back_at DATETIMEOFFSET,
+			kind NVARCHAR(16) NOT NULL DEFAULT 'sql',
+			started_at DATETIMEOFFSET,
+			created_at DATETIMEOFFSET NOT NULL DEFAULT SYSDATETIMEOFFSET()
+		);
+	`, tableName)
+}
+
+// CreateLogTableQuery implements migration.Dialect
+func (Dialect) CreateLogTableQuery(tableName string) string {
+	return fmt.Sprintf(`
+		IF OBJECT_ID(N'%[1]s', N'U') IS NULL CREATE TABLE %[1]s (
+			id BIGINT IDENTITY(1,1) PRIMARY KEY,
+			migration NVARCHAR(450) NOT NULL,
+			operation NVARCHAR(16) NOT NULL,
+			outcome NVARCHAR(16) NOT NULL,
+			error NVARCHAR(MAX),
+			duration_ms BIGINT NOT NULL,
+			host NVARCHAR(255) NOT NULL,
+			executed_by NVARCHAR(255) NOT NULL,
+			tool_version NVARCHAR(64) NOT NULL,
+			created_at DATETIMEOFFSET NOT NULL DEFAULT SYSDATETIMEOFFSET()
+		);
+	`, tableName)
+}
+
+// CreateMetaTableQuery implements migration.Dialect
+func (Dialect) CreateMetaTableQuery(tableName string) string {
+	return fmt.Sprintf(`
+		IF OBJECT_ID(N'%[1]s', N'U') IS NULL CREATE TABLE %[1]s (
+			name NVARCHAR(64) PRIMARY KEY,
+			value NVARCHAR(255) NOT NULL
+		);
+	`, tableName)
+}
+
+// TableExistsQuery implements migration.Dialect
+func (Dialect) TableExistsQuery() string {
+	return "SELECT COUNT(*) FROM INFORMATION_SCHEMA.TABLES WHERE TABLE_SCHEMA = SCHEMA_NAME() AND TABLE_NAME = ?"
+}
+
+// ColumnsQuery implements migration.Dialect
+func (Dialect) ColumnsQuery() string {
+	return "SELECT COLUMN_NAME FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = SCHEMA_NAME() AND TABLE_NAME = ?"
+}
+
+// TimestampType implements migration.Dialect
+func (Dialect) TimestampType() string { return "DATETIMEOFFSET" }
+
+// AddColumnQuery implements migration.Dialect, SQL Server does not accept
+// the COLUMN keyword in ALTER TABLE ... ADD
+func (Dialect) AddColumnQuery(tableName, column, definition string) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD %s %s", tableName, column, definition)
+}
+
+// Insert implements migration.Dialect, the driver does not support
+// LastInsertId so the id is retrieved using OUTPUT INSERTED
+func (Dialect) Insert(ctx context.Context, connection migration.Queryer, tableName string, columns []string, values []interface{}) (int64, error) {
+	var id int64
+	err := connection.QueryRowContext(ctx, db.Rebind(db.DriverMSSQL, fmt.Sprintf(
+		"INSERT INTO %s (%s) OUTPUT INSERTED.id VALUES (%s)", tableName, strings.Join(columns, ", "), migration.Placeholders(len(values)),
+	)), values...).Scan(&id)
+	return id, err
+}
+
+// Split implements migration.Dialect using SplitBatches
+func (Dialect) Split(script string) []string { return SplitBatches(script) }
+
+// TransactionalDDL implements migration.Dialect
+func (Dialect) TransactionalDDL() bool { return true }
+
+// Lock implements migration.Dialect using sp_getapplock with a session
+// lock owner so that the lock outlives individual transactions
+func (Dialect) Lock(ctx context.Context, connection *sql.Conn, name string, timeout time.Duration) error {
+	var result int64
+	err := connection.QueryRowContext(ctx, `
+		DECLARE @result INT;
+		EXEC @result = sp_getapplock @Resource = @p1, @LockMode = 'Exclusive', @LockOwner = 'Session', @LockTimeout = @p2;
+		SELECT @result;
+	`, name, timeout.Milliseconds()).Scan(&result)
+	if err != nil {
+		return err
+	} else if result == -1 {
+		return migration.ErrLockTimeout
+	} else if result < 0 {
+		return fmt.Errorf("sp_getapplock returned %v", result)
+	}
+	return nil
+}
+
+// Unlock implements migration.Dialect using sp_releaseapplock
+func (Dialect) Unlock(ctx context.Context, connection *sql.Conn, name string) error {
+	_, err := connection.ExecContext(ctx, "EXEC sp_releaseapplock @Resource = @p1, @LockOwner = 'Session'", name)
+	return err
+}
+
+// SplitBatches splits the :script parameter into batches on lines which
+// only contain the GO batch separator, as the driver cannot execute them
+// directly. A batch followed by "GO <count>" is repeated <count> times
+func SplitBatches(script string) []string {
+	return migration.SplitStatements(db.DriverMSSQL, script)
+}
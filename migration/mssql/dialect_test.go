@@ -0,0 +1,35 @@
+package mssql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/suite"
+	"github.com/usvc/go-db/migration"
+)
+
+type DialectTests struct {
+	suite.Suite
+}
+
+func TestDialect(t *testing.T) {
+	suite.Run(t, &DialectTests{})
+}
+
+func (s *DialectTests) TestLock() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	mock.ExpectQuery("EXEC @result = sp_getapplock").WithArgs("migrations_lock", int64(1500)).WillReturnRows(sqlmock.NewRows([]string{"result"}).AddRow(0))
+	mock.ExpectExec("EXEC sp_releaseapplock").WithArgs("migrations_lock").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("EXEC @result = sp_getapplock").WithArgs("migrations_lock", int64(1500)).WillReturnRows(sqlmock.NewRows([]string{"result"}).AddRow(-1))
+	engine := NewEngine("migrations", connection)
+	unlock, err := engine.Lock(context.Background(), 1500*time.Millisecond)
+	s.Nil(err)
+	s.Nil(unlock())
+	_, err = engine.Lock(context.Background(), 1500*time.Millisecond)
+	s.Equal(migration.ErrLockTimeout, err)
+	s.Nil(mock.ExpectationsWereMet())
+}
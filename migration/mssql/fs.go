@@ -0,0 +1,17 @@
+//go:build go1.16
+// +build go1.16
+
+package mssql
+
+import (
+	"io/fs"
+
+	"github.com/usvc/go-db/migration"
+)
+
+// NewFromFS loads the migrations in the directory :dir of the :fsys
+// parameter, allowing migrations to be embedded with `//go:embed`
+func NewFromFS(fsys fs.FS, dir string) (Migrations, error) {
+	migrations, err := migration.NewFromFS(fsys, dir)
+	return fromGeneric(migrations), err
+}
@@ -0,0 +1,57 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/usvc/go-db/migration"
+)
+
+// Migration is a Microsoft SQL Server schema migration, see migration.Migration
+type Migration migration.Migration
+
+// MigrationFunc is the upward or downward function of a migration written
+// in Go, see migration.MigrationFunc
+type MigrationFunc = migration.MigrationFunc
+
+func (m *Migration) Apply(tableName string, connection *sql.DB) error {
+	engine, err := requireEngine(tableName, connection)
+	if err != nil {
+		return err
+	}
+	return engine.Apply(context.Background(), (*migration.Migration)(m))
+}
+
+func (m *Migration) Rollback(tableName string, connection *sql.DB) error {
+	engine, err := requireEngine(tableName, connection)
+	if err != nil {
+		return err
+	}
+	return engine.Rollback(context.Background(), (*migration.Migration)(m))
+}
+
+func (m *Migration) Resolve(tableName string, connection *sql.DB) error {
+	engine, err := requireEngine(tableName, connection)
+	if err != nil {
+		return err
+	}
+	return engine.Resolve(context.Background(), (*migration.Migration)(m))
+}
+
+func (m *Migration) Validate(tableName string, connection *sql.DB) error {
+	engine, err := requireEngine(tableName, connection)
+	if err != nil {
+		return err
+	}
+	return engine.Validate(context.Background(), (*migration.Migration)(m))
+}
+
+// requireEngine returns the engine of the migrations table named :tableName
+// if the table is up-to-date, see migration.Engine.RequireTable
+func requireEngine(tableName string, connection *sql.DB) (*migration.Engine, error) {
+	engine := NewEngine(tableName, connection)
+	if err := engine.RequireTable(context.Background()); err != nil {
+		return nil, err
+	}
+	return engine, nil
+}
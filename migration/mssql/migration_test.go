@@ -0,0 +1,141 @@
+package mssql
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/suite"
+)
+
+type MigrationTests struct {
+	suite.Suite
+}
+
+func TestMigration(t *testing.T) {
+	suite.Run(t, &MigrationTests{})
+}
+
+// expectTable expects the migrations table to be found at the schema
+// version :version
+func (s *MigrationTests) expectTable(mock sqlmock.Sqlmock, version int) {
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM INFORMATION_SCHEMA\\.TABLES").WithArgs("migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM INFORMATION_SCHEMA\\.TABLES").WithArgs("migrations_meta").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery("SELECT value FROM migrations_meta WHERE name = @p1").WithArgs("schema_version").
+		WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow(strconv.Itoa(version)))
+}
+
+func (s *MigrationTests) TestApply() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	s.expectTable(mock, LatestSchemaVersion)
+	mock.ExpectQuery("SELECT id, status FROM migrations WHERE name = @p1").WithArgs("test_apply").WillReturnRows(sqlmock.NewRows([]string{"id", "status"}))
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO migrations (.+) OUTPUT INSERTED.id VALUES").
+		WithArgs("test_apply", sqlmock.AnyArg(), "DROP TABLE test_apply", sqlmock.AnyArg(), StatusApplying, KindSQL, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(42))
+	mock.ExpectExec("CREATE TABLE test_apply").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE INDEX test_apply_id").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE migrations SET applied_at = @p1, status = @p2 WHERE id = @p3").
+		WithArgs(sqlmock.AnyArg(), StatusApplied, 42).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec("INSERT INTO migrations_log").
+		WithArgs("test_apply", OperationApply, OutcomeSucceeded, nil, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	migration := New("test_apply", "CREATE TABLE test_apply (id INTEGER)\nGO\nCREATE INDEX test_apply_id ON test_apply (id)\n", "DROP TABLE test_apply")
+	s.Nil(migration.Apply("migrations", connection))
+	s.Equal(int64(42), migration.ID)
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *MigrationTests) TestApply_alreadyApplied() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	s.expectTable(mock, LatestSchemaVersion)
+	mock.ExpectQuery("SELECT id, status FROM migrations").WillReturnRows(sqlmock.NewRows([]string{"id", "status"}).AddRow(1, StatusApplied))
+	migration := New("test_apply", "up", "down")
+	s.Equal(NoErrAlreadyApplied, migration.Apply("migrations", connection))
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *MigrationTests) TestApply_outdatedTable() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	s.expectTable(mock, LatestSchemaVersion-1)
+	migration := New("test_apply", "up", "down")
+	err = migration.Apply("migrations", connection)
+	s.Contains(err.Error(), "call EnsureTable to upgrade it")
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *MigrationTests) TestApply_error_application() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	s.expectTable(mock, LatestSchemaVersion)
+	mock.ExpectQuery("SELECT id, status FROM migrations").WillReturnRows(sqlmock.NewRows([]string{"id", "status"}))
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO migrations").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec("CREATE TABLE test_apply_error").WillReturnError(fmt.Errorf("this is expected"))
+	mock.ExpectRollback()
+	mock.ExpectQuery("INSERT INTO migrations \\(name, up, down, checksum, status, kind, started_at, error\\) OUTPUT INSERTED\\.id").
+		WithArgs("test_apply_error", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), StatusApplying, KindSQL, sqlmock.AnyArg(), "this is expected").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	migration := New("test_apply_error", "CREATE TABLE test_apply_error (id,)", "DROP TABLE test_apply_error")
+	err = migration.Apply("migrations", connection)
+	s.Contains(err.Error(), "failed to apply migration")
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *MigrationTests) TestRollback() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	s.expectTable(mock, LatestSchemaVersion)
+	mock.ExpectQuery("SELECT id, status FROM migrations WHERE name = @p1").WithArgs("test_rollback").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status"}).AddRow(5, StatusApplied))
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE migrations SET status = @p1, started_at = @p2 WHERE id = @p3").
+		WithArgs(StatusRollingBack, sqlmock.AnyArg(), 5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DROP TABLE test_rollback").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE migrations SET status = @p1, rolled_back_at = @p2 WHERE id = @p3").
+		WithArgs(StatusRolledBack, sqlmock.AnyArg(), 5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec("INSERT INTO migrations_log").
+		WithArgs("test_rollback", OperationRollback, OutcomeSucceeded, nil, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	migration := New("test_rollback", "CREATE TABLE test_rollback (id INTEGER)", "DROP TABLE test_rollback")
+	s.Nil(migration.Rollback("migrations", connection))
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *MigrationTests) TestValidate() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	columns := []string{"id", "name", "up", "down", "checksum", "error", "status", "applied_at", "created_at", "rolled_back_at", "kind", "started_at"}
+	s.expectTable(mock, LatestSchemaVersion)
+	mock.ExpectQuery("SELECT (.+) FROM migrations").WithArgs("test_validate").
+		WillReturnRows(sqlmock.NewRows(columns))
+	s.expectTable(mock, LatestSchemaVersion)
+	mock.ExpectQuery("SELECT (.+) FROM migrations").WithArgs("test_validate").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "test_validate", "CREATE TABLE\ttest_validate (id INTEGER)", "DROP TABLE test_validate", nil, nil, StatusApplied, nil, nil, nil, KindSQL, nil))
+	s.expectTable(mock, LatestSchemaVersion)
+	mock.ExpectQuery("SELECT (.+) FROM migrations").WithArgs("test_validate").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "test_validate", "CREATE TABLE test_validate (id BIGINT)", "DROP TABLE test_validate", nil, nil, StatusApplied, nil, nil, nil, KindSQL, nil))
+	migration := New("test_validate", "CREATE TABLE test_validate (id INTEGER)", "DROP TABLE test_validate")
+	s.Equal(NoErrDoesNotExist, migration.Validate("migrations", connection))
+	s.Nil(migration.Validate("migrations", connection))
+	err = migration.Validate("migrations", connection)
+	s.Contains(err.Error(), "failed to reconcile upward migration query")
+	s.Nil(mock.ExpectationsWereMet())
+}
@@ -0,0 +1,73 @@
+package mssql
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/suite"
+)
+
+type UtilsTests struct {
+	suite.Suite
+}
+
+func TestUtils(t *testing.T) {
+	suite.Run(t, &UtilsTests{})
+}
+
+func (s *UtilsTests) TestGetMigrationNamesFromFilenames_error() {
+	migrationNames, err := GetMigrationNamesFromFilenames([]string{
+		"a.up.sql",
+		"b.up.sql",
+		"b.down.sql",
+		"randomFile",
+	})
+	s.EqualValues([]string{"b"}, migrationNames)
+	s.EqualValues([]error{
+		errors.New("could not find reverse migration for a.up.sql"),
+		errors.New("filename randomFile does not end with .{up, down}.sql"),
+	}, err)
+}
+
+func (s *UtilsTests) TestSplitBatches() {
+	s.Equal([]string{
+		"CREATE TABLE a (id INT)",
+		"INSERT INTO a VALUES (1)",
+		"INSERT INTO a VALUES (1)",
+		"CREATE VIEW b AS SELECT id FROM a\nwhere id > 0",
+	}, SplitBatches("CREATE TABLE a (id INT)\ngo\nINSERT INTO a VALUES (1)\n  GO 2  \nGO\nCREATE VIEW b AS SELECT id FROM a\nwhere id > 0\n"))
+	s.Equal([]string{"SELECT 1 AS good"}, SplitBatches("SELECT 1 AS good"))
+}
+
+func (s *UtilsTests) TestInit() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	mock.ExpectExec("IF OBJECT_ID\\(N'migrations', N'U'\\) IS NULL CREATE TABLE migrations \\(\\s+id BIGINT IDENTITY\\(1,1\\) PRIMARY KEY").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("migrations_log").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("IF OBJECT_ID\\(N'migrations_meta', N'U'\\) IS NULL CREATE TABLE migrations_meta").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT value FROM migrations_meta WHERE name = @p1").WithArgs("schema_version").
+		WillReturnRows(sqlmock.NewRows([]string{"value"}))
+	mock.ExpectQuery("(?i)SELECT column_name FROM information_schema.columns").WithArgs("migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("id").AddRow("checksum").AddRow("rolled_back_at").AddRow("kind").AddRow("started_at"))
+	mock.ExpectQuery("SELECT id, up, down FROM migrations WHERE checksum IS NULL").WillReturnRows(sqlmock.NewRows([]string{"id", "up", "down"}))
+	mock.ExpectExec("UPDATE migrations_meta SET value = @p1 WHERE name = @p2").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO migrations_meta").WithArgs("schema_version", "5").WillReturnResult(sqlmock.NewResult(1, 1))
+	s.Nil(Init("migrations", connection))
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *UtilsTests) TestNewEngine_tableExists() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	mock.ExpectQuery("TABLE_SCHEMA = SCHEMA_NAME\\(\\) AND TABLE_NAME = @p1").
+		WithArgs("migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	exists, err := NewEngine("migrations", connection).TableExists(context.Background())
+	s.Nil(err)
+	s.True(exists)
+	s.Nil(mock.ExpectationsWereMet())
+}
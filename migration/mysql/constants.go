@@ -1,14 +1,52 @@
 package mysql
 
-import "errors"
+import "github.com/usvc/go-db/migration"
 
 const (
-	StatusRollingBack = "rolling back"
-	StatusApplying    = "applying"
-	StatusApplied     = "applied"
+	StatusRollingBack = migration.StatusRollingBack
+	StatusRolledBack  = migration.StatusRolledBack
+	StatusApplying    = migration.StatusApplying
+	StatusApplied     = migration.StatusApplied
+	StatusBaselined   = migration.StatusBaselined
+
+	KindSQL = migration.KindSQL
+	KindGo  = migration.KindGo
+
+	RepairApplied = migration.RepairApplied
+	RepairFailed  = migration.RepairFailed
+	RepairRemove  = migration.RepairRemove
+	// DefaultStaleAfter is the default of Runner.StaleAfter
+	DefaultStaleAfter = migration.DefaultStaleAfter
+
+	SchemeTimestamp = migration.SchemeTimestamp
+	SchemeSequence  = migration.SchemeSequence
+
+	StatePending    = migration.StatePending
+	StateApplied    = migration.StateApplied
+	StateBaselined  = migration.StateBaselined
+	StateFailed     = migration.StateFailed
+	StateInProgress = migration.StateInProgress
+	StateStale      = migration.StateStale
+	StateRolledBack = migration.StateRolledBack
+	StateUnknown    = migration.StateUnknown
+
+	OperationApply    = migration.OperationApply
+	OperationRollback = migration.OperationRollback
+	OperationResolve  = migration.OperationResolve
+	OperationBaseline = migration.OperationBaseline
+	OperationRepair   = migration.OperationRepair
+	OutcomeSucceeded  = migration.OutcomeSucceeded
+	OutcomeFailed     = migration.OutcomeFailed
+
+	LatestSchemaVersion = migration.LatestSchemaVersion
 )
 
 var (
-	NoErrAlreadyApplied = errors.New("migration has already been applied")
-	NoErrDoesNotExist   = errors.New("migration does not exist")
+	NoErrAlreadyApplied = migration.NoErrAlreadyApplied
+	NoErrDoesNotExist   = migration.NoErrDoesNotExist
+	ErrLockTimeout      = migration.ErrLockTimeout
 )
+
+// StateError is returned when a migration is not in a state which allows
+// the requested operation, see migration.StateError
+type StateError = migration.StateError
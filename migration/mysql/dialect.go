@@ -0,0 +1,130 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/usvc/go-db"
+	"github.com/usvc/go-db/migration"
+)
+
+// Dialect implements migration.Dialect for MySQL, DDL statements cause
+// an implicit commit in MySQL so migrations are not run in transactions
+type Dialect struct{}
+
+// Driver implements migration.Dialect
+func (Dialect) Driver() string { return db.DriverMySQL }
+
+// CreateTableQuery implements migration.Dialect
+func (Dialect) CreateTableQuery(tableName string) string {
+	return fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			id INTEGER(16) UNIQUE AUTO_INCREMENT NOT NULL,
+			name VARCHAR(512) UNIQUE NOT NULL,
+			up TEXT NOT NULL,
+			down TEXT NOT NULL,
+			checksum VARCHAR(64),
+			error TEXT,
+			status VARCHAR(16) NOT NULL,
+			applied_at DATETIME,
+			rolled_back_at DATETIME,
+			kind VARCHAR(16) NOT NULL DEFAULT 'sql',
+			started_at DATETIME,
+			created_at TIMESTAMP NOT NULL DEFAULT NOW()
+		) Engine=InnoDB;
+	`, tableName)
+}
+
+// CreateLogTableQuery implements migration.Dialect
+func (Dialect) CreateLogTableQuery(tableName string) string {
+	return fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			id BIGINT UNIQUE AUTO_INCREMENT NOT NULL,
+			migration VARCHAR(512) NOT NULL,
+			operation VARCHAR(16) NOT NULL,
+			outcome VARCHAR(16) NOT NULL,
+			error TEXT,
+			duration_ms BIGINT NOT NULL,
+			host VARCHAR(255) NOT NULL,
+			executed_by VARCHAR(255) NOT NULL,
+			tool_version VARCHAR(64) NOT NULL,
+			created_at TIMESTAMP NOT NULL DEFAULT NOW()
+		) Engine=InnoDB;
+	`, tableName)
+}
+
+// CreateMetaTableQuery implements migration.Dialect
+func (Dialect) CreateMetaTableQuery(tableName string) string {
+	return fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			name VARCHAR(64) PRIMARY KEY,
+			value VARCHAR(255) NOT NULL
+		) Engine=InnoDB;
+	`, tableName)
+}
+
+// TableExistsQuery implements migration.Dialect
+func (Dialect) TableExistsQuery() string {
+	return "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?"
+}
+
+// ColumnsQuery implements migration.Dialect
+func (Dialect) ColumnsQuery() string {
+	return "SELECT column_name FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = ?"
+}
+
+// TimestampType implements migration.Dialect
+func (Dialect) TimestampType() string { return "DATETIME" }
+
+// AddColumnQuery implements migration.Dialect
+func (Dialect) AddColumnQuery(tableName, column, definition string) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", tableName, column, definition)
+}
+
+// Insert implements migration.Dialect
+func (Dialect) Insert(ctx context.Context, connection migration.Queryer, tableName string, columns []string, values []interface{}) (int64, error) {
+	res, err := connection.ExecContext(ctx, fmt.Sprintf(
+		"INSERT INTO %s (%s) VALUES (%s)", tableName, strings.Join(columns, ", "), migration.Placeholders(len(values)),
+	), values...)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// Split implements migration.Dialect using migration.SplitStatements
+func (Dialect) Split(script string) []string {
+	return migration.SplitStatements(db.DriverMySQL, script)
+}
+
+// TransactionalDDL implements migration.Dialect, MySQL implicitly commits
+// the open transaction before and after most DDL statements (CREATE,
+// ALTER, DROP, RENAME, TRUNCATE...) so a failed migration cannot be rolled
+// back and may leave a partially applied schema which has to be repaired
+// by hand before the migration is resolved
+func (Dialect) TransactionalDDL() bool { return false }
+
+// Lock implements migration.Dialect using GET_LOCK, which only supports
+// timeouts in whole seconds
+func (Dialect) Lock(ctx context.Context, connection *sql.Conn, name string, timeout time.Duration) error {
+	var acquired sql.NullInt64
+	err := connection.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", name, int64(math.Ceil(timeout.Seconds()))).Scan(&acquired)
+	if err != nil {
+		return err
+	} else if !acquired.Valid {
+		return fmt.Errorf("GET_LOCK returned NULL")
+	} else if acquired.Int64 != 1 {
+		return migration.ErrLockTimeout
+	}
+	return nil
+}
+
+// Unlock implements migration.Dialect using RELEASE_LOCK
+func (Dialect) Unlock(ctx context.Context, connection *sql.Conn, name string) error {
+	_, err := connection.ExecContext(ctx, "DO RELEASE_LOCK(?)", name)
+	return err
+}
@@ -0,0 +1,42 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/suite"
+	"github.com/usvc/go-db/migration"
+)
+
+type DialectTests struct {
+	suite.Suite
+}
+
+func TestDialect(t *testing.T) {
+	suite.Run(t, &DialectTests{})
+}
+
+func (s *DialectTests) TestLock() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	mock.ExpectQuery("SELECT GET_LOCK\\(\\?, \\?\\)").WithArgs("migrations_lock", 2).WillReturnRows(sqlmock.NewRows([]string{"lock"}).AddRow(1))
+	mock.ExpectExec("DO RELEASE_LOCK\\(\\?\\)").WithArgs("migrations_lock").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT GET_LOCK\\(\\?, \\?\\)").WithArgs("migrations_lock", 2).WillReturnRows(sqlmock.NewRows([]string{"lock"}).AddRow(0))
+	engine := NewEngine("migrations", connection)
+	unlock, err := engine.Lock(context.Background(), 1500*time.Millisecond)
+	s.Nil(err)
+	s.Nil(unlock())
+	_, err = engine.Lock(context.Background(), 1500*time.Millisecond)
+	s.Equal(migration.ErrLockTimeout, err)
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *DialectTests) TestSplit() {
+	s.Equal([]string{
+		"CREATE TABLE a (id INTEGER) Engine=InnoDB",
+		"CREATE TRIGGER t BEFORE INSERT ON a FOR EACH ROW BEGIN SET NEW.id = 1; END",
+	}, Dialect{}.Split("CREATE TABLE a (id INTEGER) Engine=InnoDB;\nDELIMITER $$\nCREATE TRIGGER t BEFORE INSERT ON a FOR EACH ROW BEGIN SET NEW.id = 1; END$$\nDELIMITER ;\n"))
+}
@@ -0,0 +1,22 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/usvc/go-db/migration"
+)
+
+// LogEntry is a row of the log of operations performed on migrations, see
+// migration.LogEntry
+type LogEntry = migration.LogEntry
+
+// LogError is returned when an operation succeeded but could not be
+// recorded in the log table, see migration.LogError
+type LogError = migration.LogError
+
+// History returns the entries of the log of the migrations table named
+// :tableName in the order they were recorded
+func History(ctx context.Context, tableName string, connection *sql.DB) ([]LogEntry, error) {
+	return NewEngine(tableName, connection).History(ctx)
+}
@@ -87,7 +87,7 @@ func (s *MigrationTests) TestApply_error_preparation() {
 	err := migration.Apply(s.migrationTable, s.connection)
 	s.NotNil(err)
 	if err != nil {
-		s.Contains(err.Error(), "failed to prepare migration")
+		s.Contains(err.Error(), "failed to apply migration")
 	}
 }
 
@@ -133,7 +133,8 @@ func (s *MigrationTests) TestRollback_error_notApplied() {
 		Down: "DROP TABLE test_rollback_ena",
 	}
 	err := migration.Rollback(s.migrationTable, s.connection)
-	s.Contains(err.Error(), "Unknown table")
+	s.IsType(&StateError{}, err)
+	s.Contains(err.Error(), "migration has not been recorded")
 }
 
 func (s *MigrationTests) TestValidate() {
@@ -179,7 +180,7 @@ func (s *MigrationTests) TestValidate_error_preparation() {
 	err := migration.Apply(s.migrationTable, s.connection)
 	s.NotNil(err)
 	if err != nil {
-		s.Contains(err.Error(), "failed to prepare migration")
+		s.Contains(err.Error(), "failed to apply migration")
 	}
 	err = migration.Validate(s.migrationTable, s.connection)
 	s.NotNil(err)
@@ -1,7 +1,9 @@
 package mysql
 
+import "github.com/usvc/go-db/migration"
+
 const (
-	MigrationExtension = ".sql"
+	MigrationExtension = migration.MigrationExtension
 )
 
 type Migrations []*Migration
@@ -12,15 +14,28 @@ func (m Migrations) Len() int { return len(m) }
 // Swap implements the sort.Interface
 func (m Migrations) Swap(i, j int) { m[i], m[j] = m[j], m[i] }
 
-// Less implements the sort.Interface
+// Less implements the sort.Interface, see migration.Migration.Before
 func (m Migrations) Less(i, j int) bool {
-	return m[i].Name < m[j].Name
+	return (*migration.Migration)(m[i]).Before((*migration.Migration)(m[j]))
+}
+
+// Generic returns the migrations for use with the migration package
+func (m Migrations) Generic() migration.Migrations {
+	migrations := make(migration.Migrations, len(m))
+	for i := 0; i < len(m); i++ {
+		migrations[i] = (*migration.Migration)(m[i])
+	}
+	return migrations
 }
 
-// func (m *Migrations) LoadFromDirectory(directoryPath, ext string) error {
-// 	files, err := ioutil.ReadDir(directoryPath)
-// 	if err != nil {
-// 		return err
-// 	}
-// 	for i
-// }
+// fromGeneric converts migrations from the migration package
+func fromGeneric(m migration.Migrations) Migrations {
+	if m == nil {
+		return nil
+	}
+	migrations := make(Migrations, len(m))
+	for i := 0; i < len(m); i++ {
+		migrations[i] = (*Migration)(m[i])
+	}
+	return migrations
+}
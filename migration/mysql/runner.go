@@ -0,0 +1,25 @@
+package mysql
+
+import (
+	"database/sql"
+
+	"github.com/usvc/go-db/migration"
+)
+
+// Runner applies a set of migrations in order, see migration.Runner
+type Runner = migration.Runner
+
+// Report describes the outcome of a run, see migration.Report
+type Report = migration.Report
+
+// Plan describes what a run would execute, see migration.Plan
+type Plan = migration.Plan
+
+// PlannedStatement is a statement of a Plan, see migration.PlannedStatement
+type PlannedStatement = migration.PlannedStatement
+
+// NewRunner returns a Runner that applies the :migrations parameter using
+// the migrations table named :tableName
+func NewRunner(migrations Migrations, tableName string, connection *sql.DB) *Runner {
+	return migration.NewRunner(NewEngine(tableName, connection), migrations.Generic())
+}
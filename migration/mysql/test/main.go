@@ -1,7 +1,12 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/usvc/go-config"
@@ -31,7 +36,7 @@ var conf = config.Map{
 	},
 }
 
-var migrations = []*mysql.Migration{
+var migrations = mysql.Migrations{
 	mysql.New(
 		"create_table",
 		`CREATE TABLE mesa (
@@ -91,53 +96,222 @@ func main() {
 			}
 		},
 	})
-	rootCommand.AddCommand(&cobra.Command{
+	applyCommand := &cobra.Command{
 		Use: "apply",
 		Run: func(cmd *cobra.Command, args []string) {
 			db.Init(getDBOptions())
-			connection := db.Get()
-			for i := 0; i < len(migrations); i++ {
-				migration := migrations[i]
-				err := migration.Validate(migrationTableName, connection)
-				if err != nil && err != mysql.NoErrDoesNotExist {
+			runner := mysql.NewRunner(migrations, migrationTableName, db.Get())
+			runner.ResolveFailed = true
+			if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+				plan, err := runner.Plan(context.Background())
+				if err != nil {
 					fmt.Println(err)
-					err = migration.Resolve(migrationTableName, connection)
-					if err != nil {
+					return
+				}
+				if file, _ := cmd.Flags().GetString("file"); len(file) > 0 {
+					if err := plan.WriteFile(file); err != nil {
 						fmt.Println(err)
-						break
 					}
+					return
 				}
-				err = migration.Apply(migrationTableName, connection)
+				plan.WriteTo(os.Stdout)
+				return
+			}
+			report, err := runner.Apply(context.Background())
+			for i := 0; i < len(report.Skipped); i++ {
+				fmt.Printf("[apply:%s] migration already applied\n", report.Skipped[i].Name)
+			}
+			for i := 0; i < len(report.Applied); i++ {
+				fmt.Printf("[apply:%s] migration applied\n", report.Applied[i].Name)
+			}
+			for i := 0; i < len(report.Warnings); i++ {
+				fmt.Println(report.Warnings[i])
+			}
+			if err != nil {
+				fmt.Println(err)
+			}
+		},
+	}
+	applyCommand.Flags().Bool("dry-run", false, "print the statements which would be executed without executing them")
+	applyCommand.Flags().StringP("file", "f", "", "write the statements of --dry-run to this file instead of printing them")
+	rootCommand.AddCommand(applyCommand)
+	baselineCommand := &cobra.Command{
+		Use: "baseline",
+		Run: func(cmd *cobra.Command, args []string) {
+			db.Init(getDBOptions())
+			version, _ := cmd.Flags().GetInt64("version")
+			report, err := mysql.NewRunner(migrations, migrationTableName, db.Get()).Baseline(context.Background(), version)
+			for i := 0; i < len(report.Skipped); i++ {
+				fmt.Printf("[baseline:%s] migration already applied\n", report.Skipped[i].Name)
+			}
+			for i := 0; i < len(report.Baselined); i++ {
+				fmt.Printf("[baseline:%s] migration baselined\n", report.Baselined[i].Name)
+			}
+			for i := 0; i < len(report.Warnings); i++ {
+				fmt.Println(report.Warnings[i])
+			}
+			if err != nil {
+				fmt.Println(err)
+			}
+		},
+	}
+	baselineCommand.Flags().Int64("version", 0, "version of the latest migration already reflected in the schema")
+	rootCommand.AddCommand(baselineCommand)
+	createCommand := &cobra.Command{
+		Use:  "create <name>",
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			dir, _ := cmd.Flags().GetString("dir")
+			scheme, _ := cmd.Flags().GetString("scheme")
+			createdMigration, err := mysql.NewMigrationFiles(dir, args[0], scheme)
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+			fmt.Printf("[create:%s] created %s.up.sql and %s.down.sql\n", createdMigration.Name, createdMigration.Path, createdMigration.Path)
+		},
+	}
+	createCommand.Flags().String("dir", "./migrations", "directory to create the migration files in")
+	createCommand.Flags().String("scheme", mysql.SchemeTimestamp, "versioning scheme of the migration, one of timestamp or sequence")
+	rootCommand.AddCommand(createCommand)
+	repairCommand := &cobra.Command{
+		Use:  "repair [name]",
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			db.Init(getDBOptions())
+			runner := mysql.NewRunner(migrations, migrationTableName, db.Get())
+			runner.StaleAfter, _ = cmd.Flags().GetDuration("stale-after")
+			if len(args) == 0 {
+				stale, err := runner.Stale(context.Background())
 				if err != nil {
-					if err == mysql.NoErrAlreadyApplied {
-						fmt.Printf("[apply:%s] migration already applied\n", migration.Name)
-						continue
-					}
 					fmt.Println(err)
+					return
 				}
+				for i := 0; i < len(stale); i++ {
+					fmt.Printf("[repair:%s] migration is stale in status '%s'\n", stale[i].Name, stale[i].Status)
+				}
+				return
 			}
+			action, _ := cmd.Flags().GetString("mark")
+			if err := runner.Repair(context.Background(), args[0], action); err != nil {
+				fmt.Println(err)
+				return
+			}
+			fmt.Printf("[repair:%s] migration repaired as '%s'\n", args[0], action)
 		},
-	})
-	rootCommand.AddCommand(&cobra.Command{
+	}
+	repairCommand.Flags().String("mark", mysql.RepairFailed, "repair action for the named migration, one of applied, failed or remove")
+	repairCommand.Flags().Duration("stale-after", mysql.DefaultStaleAfter, "duration after which a migration in progress is considered stale")
+	rootCommand.AddCommand(repairCommand)
+	rollbackCommand := &cobra.Command{
 		Use: "rollback",
 		Run: func(cmd *cobra.Command, args []string) {
 			db.Init(getDBOptions())
-			connection := db.Get()
-			for i := 0; i < len(migrations); i++ {
-				migration := migrations[i]
-				err := migration.Validate(migrationTableName, connection)
-				if err != nil {
-					if err == mysql.NoErrDoesNotExist {
-						fmt.Printf("[rollback:%s] %s", migration.Name, err)
-						break
-					}
-				}
-				err = migration.Rollback(migrationTableName, connection)
-				if err != nil {
-					fmt.Println(err)
-				}
+			runner := mysql.NewRunner(migrations, migrationTableName, db.Get())
+			var report *mysql.Report
+			var err error
+			if to, _ := cmd.Flags().GetString("to"); len(to) > 0 {
+				report, err = runner.RollbackTo(context.Background(), to)
+			} else {
+				steps, _ := cmd.Flags().GetInt("steps")
+				report, err = runner.RollbackSteps(context.Background(), steps)
+			}
+			for i := 0; i < len(report.RolledBack); i++ {
+				fmt.Printf("[rollback:%s] migration rolled back\n", report.RolledBack[i].Name)
+			}
+			for i := 0; i < len(report.Warnings); i++ {
+				fmt.Println(report.Warnings[i])
+			}
+			if err != nil {
+				fmt.Println(err)
 			}
 		},
-	})
+	}
+	rollbackCommand.Flags().String("to", "", "name of the migration to roll back to, migrations applied after it are rolled back")
+	rollbackCommand.Flags().Int("steps", 1, "number of migrations to roll back, ignored if --to is set")
+	rootCommand.AddCommand(rollbackCommand)
+	statusCommand := &cobra.Command{
+		Use: "status",
+		Run: func(cmd *cobra.Command, args []string) {
+			db.Init(getDBOptions())
+			runner := mysql.NewRunner(migrations, migrationTableName, db.Get())
+			runner.StaleAfter, _ = cmd.Flags().GetDuration("stale-after")
+			statuses, err := runner.Status(context.Background())
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+			if output, _ := cmd.Flags().GetString("output"); output == "json" {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				encoder.Encode(statuses)
+				return
+			}
+			printStatuses(statuses)
+		},
+	}
+	statusCommand.Flags().StringP("output", "o", "table", "output format, one of: table, json")
+	statusCommand.Flags().Duration("stale-after", mysql.DefaultStaleAfter, "duration after which a migration in progress is considered stale")
+	rootCommand.AddCommand(statusCommand)
+	historyCommand := &cobra.Command{
+		Use: "history",
+		Run: func(cmd *cobra.Command, args []string) {
+			db.Init(getDBOptions())
+			entries, err := mysql.History(context.Background(), migrationTableName, db.Get())
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+			if output, _ := cmd.Flags().GetString("output"); output == "json" {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				encoder.Encode(entries)
+				return
+			}
+			printHistory(entries)
+		},
+	}
+	historyCommand.Flags().StringP("output", "o", "table", "output format, one of: table, json")
+	rootCommand.AddCommand(historyCommand)
 	rootCommand.Execute()
 }
+
+func printStatuses(statuses []mysql.MigrationStatus) {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "NAME\tKIND\tSTATE\tAPPLIED AT\tCHECKSUM\tERROR")
+	for i := 0; i < len(statuses); i++ {
+		status := statuses[i]
+		appliedAt, checksum, errorText := "-", "-", "-"
+		if status.AppliedAt != nil {
+			appliedAt = status.AppliedAt.Format(time.RFC3339)
+		}
+		if status.Local != nil && status.Remote != nil {
+			checksum = "ok"
+			if !status.ChecksumMatch {
+				checksum = "mismatch"
+			}
+		}
+		if status.Error != nil && len(*status.Error) > 0 {
+			errorText = *status.Error
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\n", status.Name, status.Kind, status.State, appliedAt, checksum, errorText)
+	}
+	writer.Flush()
+}
+
+func printHistory(entries []mysql.LogEntry) {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "CREATED AT\tMIGRATION\tOPERATION\tOUTCOME\tDURATION\tEXECUTED BY\tVERSION\tERROR")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		createdAt, errorText := "-", "-"
+		if entry.CreatedAt != nil {
+			createdAt = entry.CreatedAt.Format(time.RFC3339)
+		}
+		if entry.Error != nil && len(*entry.Error) > 0 {
+			errorText = *entry.Error
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s@%s\t%s\t%s\n", createdAt, entry.Migration, entry.Operation, entry.Outcome, entry.Duration, entry.ExecutedBy, entry.Host, entry.ToolVersion, errorText)
+	}
+	writer.Flush()
+}
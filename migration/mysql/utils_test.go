@@ -1,6 +1,7 @@
 package mysql
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -69,6 +70,13 @@ func (s *UtilsTests) SetupTest() {
 	Init(s.migrationTable, s.connection)
 }
 
+func (s *UtilsTests) TestEnsureTable() {
+	s.Nil(EnsureTable(s.migrationTable, s.connection))
+	version, err := NewEngine(s.migrationTable, s.connection).SchemaVersion(context.Background())
+	s.Nil(err)
+	s.Equal(LatestSchemaVersion, version)
+}
+
 func (s *UtilsTests) TestGetMigrationNamesFromFilenames() {
 	migrationNames, err := GetMigrationNamesFromFilenames(
 		[]string{
@@ -164,17 +172,3 @@ func (s *UtilsTests) TestNewFromDirectory_error() {
 	s.Contains(err.Error(), "not an sql file")
 	s.Len(migrations, 1)
 }
-
-func (s *UtilsTests) Test_stringEndsWith() {
-	s.True(stringEndsWith("a.up.sql", ".up.sql"))
-	s.False(stringEndsWith("a.sql", ".up.sql"))
-	s.False(stringEndsWith("a.up", ".up.sql"))
-	s.False(stringEndsWith("a", ".up.sql"))
-	s.False(stringEndsWith("Makefile", ".up.sql"))
-}
-
-func (s *UtilsTests) Test_stringSliceContains() {
-	s.True(stringSliceContains([]string{"a", "b"}, "b"))
-	s.True(stringSliceContains([]string{"a", "b"}, "a"))
-	s.False(stringSliceContains([]string{"a", "b"}, "c"))
-}
@@ -0,0 +1,313 @@
+package migration
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/usvc/go-db"
+)
+
+// PlannedStatement is a statement which would be executed by a run, see
+// Runner.Plan
+type PlannedStatement struct {
+	// Migration is the name of the migration the statement is executed
+	// for, it is empty for statements which create or upgrade the
+	// migrations table
+	Migration string `json:"migration" yaml:"migration"`
+	// Bookkeeping is true if the statement maintains the migrations table
+	// instead of being part of a migration script
+	Bookkeeping bool `json:"bookkeeping" yaml:"bookkeeping"`
+	// Query is the statement with its parameters written as literals
+	Query string `json:"query" yaml:"query"`
+	// Function is true if the statement stands for the upward function of
+	// the migration, which cannot be planned as SQL, see NewFunc
+	Function bool `json:"function" yaml:"function"`
+}
+
+// Plan describes what a run would execute without executing it, see
+// Runner.Plan
+type Plan struct {
+	// Driver is the name of the driver the statements are written for
+	Driver string `json:"driver" yaml:"driver"`
+	// TableName is the name of the migrations table
+	TableName string `json:"table_name" yaml:"table_name"`
+	// Pending contains the migrations which would be applied, in order
+	Pending Migrations `json:"pending" yaml:"pending"`
+	// Skipped contains the migrations which have already been applied
+	Skipped Migrations `json:"skipped" yaml:"skipped"`
+	// Statements contains the statements which would be executed, in order
+	Statements []PlannedStatement `json:"statements" yaml:"statements"`
+}
+
+// Plan computes the migrations which Apply would apply and the statements
+// it would execute for them, including the statements which create,
+// and maintain the migrations table, without executing anything
+// or acquiring the migration lock. Rows are identified by name in the
+// planned bookkeeping statements since their ids are assigned on insert,
+// and timestamps are written as CURRENT_TIMESTAMP. Entries of the log
+// table are not planned as they depend on the outcome of each statement.
+// The plan reflects the migrations table at the time it is computed, a
+// migrations table below LatestSchemaVersion must be upgraded (see
+// Engine.EnsureTable) before a plan can be computed
+func (r *Runner) Plan(ctx context.Context) (*Plan, error) {
+	migrations := make(Migrations, len(r.Migrations))
+	copy(migrations, r.Migrations)
+	sort.Sort(migrations)
+	if err := migrations.Validate(); err != nil {
+		return nil, err
+	}
+	plan := &Plan{Driver: r.Engine.Dialect.Driver(), TableName: r.Engine.TableName}
+	exists, err := r.Engine.TableExists(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if migrations table '%s' exists: '%s'", r.Engine.TableName, err)
+	}
+	recorded := map[string]*Migration{}
+	if !exists {
+		plan.add("", true, r.Engine.Dialect.CreateTableQuery(r.Engine.TableName))
+		plan.add("", true, r.Engine.Dialect.CreateLogTableQuery(r.Engine.LogTableName()))
+		plan.add("", true, r.Engine.Dialect.CreateMetaTableQuery(r.Engine.MetaTableName()))
+		plan.add("", true, r.Engine.inline("INSERT INTO %s_meta (name, value) VALUES (?, ?)", metaSchemaVersion, strconv.Itoa(LatestSchemaVersion)))
+	} else {
+		version, err := r.Engine.SchemaVersion(ctx)
+		if err != nil {
+			return nil, err
+		} else if version != LatestSchemaVersion {
+			return nil, fmt.Errorf("failed to plan migrations: migrations table '%s' has schema version %v instead of %v, upgrade it before planning", r.Engine.TableName, version, LatestSchemaVersion)
+		}
+		history, err := r.Engine.List(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if !r.AllowOutOfOrder {
+			if err := checkOrder(migrations, history); err != nil {
+				return nil, err
+			}
+		}
+		for i := 0; i < len(history); i++ {
+			recorded[history[i].Name] = history[i]
+		}
+	}
+	for i := 0; i < len(migrations); i++ {
+		migration := migrations[i]
+		reuse := false
+		if remoteMigration, ok := recorded[migration.Name]; ok {
+			err := r.Engine.compare(migration, remoteMigration)
+			if err == nil {
+				plan.Skipped = append(plan.Skipped, migration)
+				continue
+			} else if err == NoErrDoesNotExist {
+				reuse = true
+			} else if r.ResolveFailed && remoteMigration.Error != nil {
+				plan.add(migration.Name, true, r.Engine.inline("DELETE FROM %s WHERE name = ?", migration.Name))
+			} else {
+				return nil, err
+			}
+		}
+		plan.Pending = append(plan.Pending, migration)
+		r.Engine.planApply(plan, migration, reuse)
+	}
+	return plan, nil
+}
+
+// WriteTo writes the statements of the plan to the :w parameter as a
+// script which can be reviewed and executed with the client of the
+// database, implementing io.WriterTo
+func (p *Plan) WriteTo(w io.Writer) (int64, error) {
+	writer := &countingWriter{writer: w}
+	fmt.Fprintf(writer, "-- plan for the migrations table '%s' (%s): %v pending migration(s)\n", p.TableName, p.Driver, len(p.Pending))
+	fmt.Fprintf(writer, "-- generated by github.com/usvc/go-db/migration %s\n", Version)
+	migration := "\x00"
+	for i := 0; i < len(p.Statements); i++ {
+		if p.Statements[i].Migration != migration {
+			migration = p.Statements[i].Migration
+			if len(migration) == 0 {
+				fmt.Fprintf(writer, "\n-- migrations table\n")
+			} else {
+				fmt.Fprintf(writer, "\n-- migration: %s\n", migration)
+			}
+		}
+		if p.Statements[i].Function {
+			fmt.Fprintf(writer, "-- executes the Go function of migration '%s'\n", migration)
+			continue
+		}
+		fmt.Fprint(writer, p.terminate(p.Statements[i].Query))
+	}
+	return writer.count, writer.err
+}
+
+// WriteFile writes the statements of the plan to the file at :path, see
+// WriteTo
+func (p *Plan) WriteFile(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create plan file '%s': '%s'", path, err)
+	}
+	buffer := bufio.NewWriter(file)
+	if _, err := p.WriteTo(buffer); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write plan file '%s': '%s'", path, err)
+	}
+	if err := buffer.Flush(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write plan file '%s': '%s'", path, err)
+	}
+	return file.Close()
+}
+
+// add appends the :query parameter to the statements of the plan without
+// its trailing delimiter
+func (p *Plan) add(migration string, bookkeeping bool, query string) {
+	p.Statements = append(p.Statements, PlannedStatement{
+		Migration:   migration,
+		Bookkeeping: bookkeeping,
+		Query:       strings.TrimSuffix(strings.TrimSpace(query), DefaultDelimiter),
+	})
+}
+
+// terminate returns the :query parameter followed by the delimiter of the
+// driver, MySQL statements which contain the delimiter themselves (such as
+// stored procedures) are wrapped in DELIMITER commands
+func (p *Plan) terminate(query string) string {
+	switch p.Driver {
+	case db.DriverMSSQL:
+		return query + "\nGO\n"
+	case db.DriverMySQL:
+		if len(SplitStatements(p.Driver, query)) > 1 {
+			return "DELIMITER $$\n" + query + "$$\nDELIMITER " + DefaultDelimiter + "\n"
+		}
+	}
+	return query + DefaultDelimiter + "\n"
+}
+
+// planApply adds the statements Apply would execute for the :m parameter
+// to the :plan parameter, the recorded row is reused if the :reuse
+// parameter is true
+func (e *Engine) planApply(plan *Plan, m *Migration, reuse bool) {
+	checksum := Checksum(e.Dialect.Driver(), m)
+	transactional := e.transactional(m, m.Up, m.UpFunc)
+	if transactional {
+		plan.add(m.Name, true, beginQuery(e.Dialect.Driver()))
+	}
+	if reuse {
+		plan.add(m.Name, true, e.inline(
+			"UPDATE %s SET up = ?, down = ?, checksum = ?, status = ?, kind = ?, started_at = CURRENT_TIMESTAMP, error = NULL, applied_at = NULL, rolled_back_at = NULL WHERE name = ?",
+			m.Up, m.Down, checksum, StatusApplying, m.kind(), m.Name,
+		))
+	} else {
+		plan.add(m.Name, true, e.inline(
+			"INSERT INTO %s (name, up, down, checksum, status, kind, started_at) VALUES (?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)",
+			m.Name, m.Up, m.Down, checksum, StatusApplying, m.kind(),
+		))
+	}
+	if m.UpFunc != nil {
+		plan.Statements = append(plan.Statements, PlannedStatement{Migration: m.Name, Function: true})
+	}
+	statements := e.Dialect.Split(m.Up)
+	for i := 0; m.UpFunc == nil && i < len(statements); i++ {
+		plan.add(m.Name, false, statements[i])
+	}
+	plan.add(m.Name, true, e.inline("UPDATE %s SET applied_at = CURRENT_TIMESTAMP, status = ? WHERE name = ?", StatusApplied, m.Name))
+	if transactional {
+		plan.add(m.Name, true, commitQuery(e.Dialect.Driver()))
+	}
+}
+
+// inline formats the :format parameter with the migrations table name and
+// replaces its placeholders with the :args parameter written as literals,
+// the format must not contain question marks other than placeholders
+func (e *Engine) inline(format string, args ...interface{}) string {
+	parts := strings.Split(fmt.Sprintf(format, e.TableName), "?")
+	var query strings.Builder
+	for i := 0; i < len(parts); i++ {
+		query.WriteString(parts[i])
+		if i < len(args) && i < len(parts)-1 {
+			query.WriteString(literal(e.Dialect.Driver(), args[i]))
+		}
+	}
+	return query.String()
+}
+
+// literal returns the :value parameter as a SQL literal for the :driver
+// parameter
+func literal(driver string, value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		if driver == db.DriverMSSQL {
+			return mssqlLiteral(value)
+		} else if driver == db.DriverMySQL {
+			value = strings.Replace(value, `\`, `\\`, -1)
+		}
+		return "'" + strings.Replace(value, "'", "''", -1) + "'"
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// mssqlLiteral returns the :value parameter as a Microsoft SQL Server
+// string literal. sqlcmd and SSMS end a batch at every GO line, including
+// lines inside string literals, so the line breaks before such lines are
+// written as NCHAR(10) and the literal is split around them
+func mssqlLiteral(value string) string {
+	lines := strings.Split(strings.Replace(value, "'", "''", -1), "\n")
+	var literal strings.Builder
+	split := false
+	literal.WriteString("N'")
+	for i := 0; i < len(lines); i++ {
+		if i > 0 && batchSeparator.MatchString(lines[i]) {
+			literal.WriteString("' + NCHAR(10) + N'")
+			split = true
+		} else if i > 0 {
+			literal.WriteString("\n")
+		}
+		literal.WriteString(lines[i])
+	}
+	literal.WriteString("'")
+	if split {
+		return "CAST(N'' AS NVARCHAR(MAX)) + " + literal.String()
+	}
+	return literal.String()
+}
+
+// beginQuery returns the statement which begins a transaction on the
+// :driver parameter
+func beginQuery(driver string) string {
+	if driver == db.DriverMSSQL {
+		return "BEGIN TRANSACTION"
+	}
+	return "BEGIN"
+}
+
+// commitQuery returns the statement which commits a transaction on the
+// :driver parameter
+func commitQuery(driver string) string {
+	if driver == db.DriverMSSQL {
+		return "COMMIT TRANSACTION"
+	}
+	return "COMMIT"
+}
+
+// countingWriter counts the bytes written to its writer and keeps the
+// first error so that WriteTo can write without checking every call
+type countingWriter struct {
+	writer io.Writer
+	count  int64
+	err    error
+}
+
+// Write implements io.Writer
+func (w *countingWriter) Write(data []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	n, err := w.writer.Write(data)
+	w.count += int64(n)
+	w.err = err
+	return n, err
+}
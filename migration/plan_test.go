@@ -0,0 +1,221 @@
+package migration
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/suite"
+	"github.com/usvc/go-db"
+)
+
+type PlanTests struct {
+	suite.Suite
+}
+
+func TestPlan(t *testing.T) {
+	suite.Run(t, &PlanTests{})
+}
+
+func (s *PlanTests) expectSchemaVersion(mock sqlmock.Sqlmock, version int) {
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM tables").WithArgs("migrations_meta").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery("SELECT value FROM migrations_meta").WithArgs("schema_version").
+		WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow(strconv.Itoa(version)))
+}
+
+func (s *PlanTests) TestPlan_newTable() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM tables").WithArgs("migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	migrations := Migrations{
+		New("2_b", "-- +migrate NoTransaction\nUP b", "DOWN b"),
+		New("1_a", "UP 1\nGO\nUP 'it''s'", "DOWN a"),
+	}
+	plan, err := NewRunner(NewEngine(testDialect{transactional: true}, "migrations", connection), migrations).Plan(context.Background())
+	s.Nil(err)
+	s.Len(plan.Pending, 2)
+	s.Equal("1_a", plan.Pending[0].Name)
+	s.Empty(plan.Skipped)
+	queries := []string{}
+	for i := 0; i < len(plan.Statements); i++ {
+		queries = append(queries, plan.Statements[i].Query)
+	}
+	s.Equal([]string{
+		"CREATE TABLE migrations",
+		"CREATE TABLE migrations_log",
+		"CREATE TABLE migrations_meta",
+		"INSERT INTO migrations_meta (name, value) VALUES ('schema_version', '5')",
+		"BEGIN",
+		"INSERT INTO migrations (name, up, down, checksum, status, kind, started_at) VALUES ('1_a', 'UP 1\nGO\nUP ''it''''s''', 'DOWN a', '" + Checksum(db.DriverPostgreSQL, migrations[1]) + "', 'applying', 'sql', CURRENT_TIMESTAMP)",
+		"UP 1",
+		"UP 'it''s'",
+		"UPDATE migrations SET applied_at = CURRENT_TIMESTAMP, status = 'applied' WHERE name = '1_a'",
+		"COMMIT",
+		"INSERT INTO migrations (name, up, down, checksum, status, kind, started_at) VALUES ('2_b', '-- +migrate NoTransaction\nUP b', 'DOWN b', '" + Checksum(db.DriverPostgreSQL, migrations[0]) + "', 'applying', 'sql', CURRENT_TIMESTAMP)",
+		"-- +migrate NoTransaction\nUP b",
+		"UPDATE migrations SET applied_at = CURRENT_TIMESTAMP, status = 'applied' WHERE name = '2_b'",
+	}, queries)
+	s.True(plan.Statements[5].Bookkeeping)
+	s.False(plan.Statements[6].Bookkeeping)
+	s.Equal("", plan.Statements[0].Migration)
+	s.Equal("1_a", plan.Statements[6].Migration)
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *PlanTests) TestPlan_batches() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM tables").WithArgs("migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	migrations := Migrations{New("1_a", "UP 1\nGO\nUP 2", "DOWN 1\nGO\nDOWN 2")}
+	plan, err := NewRunner(NewEngine(testDialect{driver: db.DriverMSSQL}, "migrations", connection), migrations).Plan(context.Background())
+	s.Nil(err)
+	s.Contains(plan.Statements[4].Query, "VALUES (N'1_a', CAST(N'' AS NVARCHAR(MAX)) + N'UP 1' + NCHAR(10) + N'GO\nUP 2', CAST(N'' AS NVARCHAR(MAX)) + N'DOWN 1' + NCHAR(10) + N'GO\nDOWN 2',")
+	var script bytes.Buffer
+	_, err = plan.WriteTo(&script)
+	s.Nil(err)
+	separators := 0
+	lines := strings.Split(script.String(), "\n")
+	for i := 0; i < len(lines); i++ {
+		if batchSeparator.MatchString(lines[i]) {
+			separators++
+		}
+	}
+	s.Equal(len(plan.Statements), separators)
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *PlanTests) TestPlan_existingTable() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	appliedAt := time.Now()
+	columns := []string{"id", "name", "up", "down", "checksum", "error", "status", "applied_at", "created_at", "rolled_back_at", "kind", "started_at"}
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM tables").WithArgs("migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	s.expectSchemaVersion(mock, LatestSchemaVersion)
+	mock.ExpectQuery("SELECT (.+) FROM migrations ORDER BY id").WillReturnRows(sqlmock.NewRows(columns).
+		AddRow(1, "1_a", "UP a", "DOWN a", nil, nil, StatusApplied, appliedAt, appliedAt, nil, KindSQL, nil).
+		AddRow(2, "2_b", "UP b", "DOWN b", nil, "this is expected", StatusApplying, nil, appliedAt, nil, KindSQL, nil).
+		AddRow(3, "3_c", "UP c", "DOWN c", nil, nil, StatusRolledBack, appliedAt, appliedAt, appliedAt, KindSQL, nil))
+	migrations := Migrations{New("1_a", "UP a", "DOWN a"), New("2_b", "UP b", "DOWN b"), New("3_c", "UP c", "DOWN c")}
+	runner := NewRunner(NewEngine(testDialect{}, "migrations", connection), migrations)
+	runner.ResolveFailed = true
+	plan, err := runner.Plan(context.Background())
+	s.Nil(err)
+	s.Len(plan.Skipped, 1)
+	s.Len(plan.Pending, 2)
+	s.Equal("DELETE FROM migrations WHERE name = '2_b'", plan.Statements[0].Query)
+	s.Contains(plan.Statements[1].Query, "INSERT INTO migrations")
+	s.Equal("UPDATE migrations SET up = 'UP c', down = 'DOWN c', checksum = '"+Checksum(db.DriverPostgreSQL, migrations[2])+"', status = 'applying', kind = 'sql', started_at = CURRENT_TIMESTAMP, error = NULL, applied_at = NULL, rolled_back_at = NULL WHERE name = '3_c'", plan.Statements[4].Query)
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *PlanTests) TestPlan_error_failed() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	columns := []string{"id", "name", "up", "down", "checksum", "error", "status", "applied_at", "created_at", "rolled_back_at", "kind", "started_at"}
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM tables").WithArgs("migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	s.expectSchemaVersion(mock, LatestSchemaVersion)
+	mock.ExpectQuery("SELECT (.+) FROM migrations ORDER BY id").WillReturnRows(sqlmock.NewRows(columns).
+		AddRow(1, "a", "UP a", "DOWN a", "checksum", "this is expected", StatusApplying, nil, nil, nil, KindSQL, nil))
+	plan, err := NewRunner(NewEngine(testDialect{}, "migrations", connection), Migrations{New("a", "UP a", "DOWN a")}).Plan(context.Background())
+	s.Nil(plan)
+	s.Contains(err.Error(), "recorded as failed")
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *PlanTests) TestPlan_error_version() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM tables").WithArgs("migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	s.expectSchemaVersion(mock, LatestSchemaVersion-1)
+	_, err = NewRunner(NewEngine(testDialect{}, "migrations", connection), nil).Plan(context.Background())
+	s.Contains(err.Error(), "has schema version 4 instead of 5, upgrade it before planning")
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *PlanTests) TestPlan_func() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM tables").WithArgs("migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	migrations := Migrations{NewFunc("1_a", func(context.Context, *sql.Tx) error { return nil }, nil)}
+	plan, err := NewRunner(NewEngine(testDialect{}, "migrations", connection), migrations).Plan(context.Background())
+	s.Nil(err)
+	s.Len(plan.Statements, 9)
+	s.Equal("BEGIN", plan.Statements[4].Query)
+	s.Contains(plan.Statements[5].Query, "'applying', 'go', CURRENT_TIMESTAMP)")
+	s.True(plan.Statements[6].Function)
+	s.Equal("COMMIT", plan.Statements[8].Query)
+	var buffer bytes.Buffer
+	plan.WriteTo(&buffer)
+	s.Contains(buffer.String(), "'go', CURRENT_TIMESTAMP);\n-- executes the Go function of migration '1_a'\nUPDATE migrations")
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *PlanTests) TestWriteTo() {
+	plan := &Plan{
+		Driver:    db.DriverMySQL,
+		TableName: "migrations",
+		Pending:   Migrations{New("a", "", "")},
+		Statements: []PlannedStatement{
+			{Bookkeeping: true, Query: "CREATE TABLE migrations (id INTEGER)"},
+			{Migration: "a", Bookkeeping: true, Query: "INSERT INTO migrations (name) VALUES ('a;b')"},
+			{Migration: "a", Query: "CREATE PROCEDURE p() BEGIN SELECT 1; SELECT 2; END"},
+		},
+	}
+	var buffer bytes.Buffer
+	n, err := plan.WriteTo(&buffer)
+	s.Nil(err)
+	s.Equal(int64(buffer.Len()), n)
+	s.Equal("-- plan for the migrations table 'migrations' (mysql): 1 pending migration(s)\n"+
+		"-- generated by github.com/usvc/go-db/migration "+Version+"\n"+
+		"\n-- migrations table\n"+
+		"CREATE TABLE migrations (id INTEGER);\n"+
+		"\n-- migration: a\n"+
+		"INSERT INTO migrations (name) VALUES ('a;b');\n"+
+		"DELIMITER $$\nCREATE PROCEDURE p() BEGIN SELECT 1; SELECT 2; END$$\nDELIMITER ;\n", buffer.String())
+	plan.Driver = db.DriverMSSQL
+	buffer.Reset()
+	plan.WriteTo(&buffer)
+	s.Contains(buffer.String(), "CREATE TABLE migrations (id INTEGER)\nGO\n")
+}
+
+func (s *PlanTests) TestWriteFile() {
+	directory, err := ioutil.TempDir("", "plan")
+	s.Nil(err)
+	defer os.RemoveAll(directory)
+	plan := &Plan{Driver: db.DriverPostgreSQL, TableName: "migrations", Statements: []PlannedStatement{{Query: "SELECT 1"}}}
+	path := filepath.Join(directory, "plan.sql")
+	s.Nil(plan.WriteFile(path))
+	contents, err := ioutil.ReadFile(path)
+	s.Nil(err)
+	s.Contains(string(contents), "\n-- migrations table\nSELECT 1;\n")
+}
+
+func (s *PlanTests) TestLiteral() {
+	s.Equal("NULL", literal(db.DriverPostgreSQL, nil))
+	s.Equal("42", literal(db.DriverPostgreSQL, int64(42)))
+	s.Equal(`'a''b\c'`, literal(db.DriverPostgreSQL, `a'b\c`))
+	s.Equal(`'a''b\\c'`, literal(db.DriverMySQL, `a'b\c`))
+	s.Equal(`N'a''b'`, literal(db.DriverMSSQL, `a'b`))
+	s.Equal("CAST(N'' AS NVARCHAR(MAX)) + N'a' + NCHAR(10) + N'GO\nb' + NCHAR(10) + N' go 2'", literal(db.DriverMSSQL, "a\nGO\nb\n go 2"))
+}
@@ -0,0 +1,130 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"time"
+
+	"github.com/usvc/go-db"
+	"github.com/usvc/go-db/migration"
+)
+
+// Dialect implements migration.Dialect for PostgreSQL, migrations are run
+// in transactions so that a failed migration does not leave a partially
+// migrated schema behind
+type Dialect struct{}
+
+// Driver implements migration.Dialect
+func (Dialect) Driver() string { return db.DriverPostgreSQL }
+
+// CreateTableQuery implements migration.Dialect
+func (Dialect) CreateTableQuery(tableName string) string {
+	return fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			id BIGSERIAL PRIMARY KEY,
+			name VARCHAR(512) UNIQUE NOT NULL,
+			up TEXT NOT NULL,
+			down TEXT NOT NULL,
+			checksum VARCHAR(64),
+			error TEXT,
+			status VARCHAR(16) NOT NULL,
+			applied_at TIMESTAMPTZ,
+			rolled_back_at TIMESTAMPTZ,
+			kind VARCHAR(16) NOT NULL DEFAULT 'sql',
+			started_at TIMESTAMPTZ,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		);
+	`, tableName)
+}
+
+// CreateLogTableQuery implements migration.Dialect
+func (Dialect) CreateLogTableQuery(tableName string) string {
+	return fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			id BIGSERIAL PRIMARY KEY,
+			migration VARCHAR(512) NOT NULL,
+			operation VARCHAR(16) NOT NULL,
+			outcome VARCHAR(16) NOT NULL,
+			error TEXT,
+			duration_ms BIGINT NOT NULL,
+			host VARCHAR(255) NOT NULL,
+			executed_by VARCHAR(255) NOT NULL,
+			tool_version VARCHAR(64) NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		);
+	`, tableName)
+}
+
+// CreateMetaTableQuery implements migration.Dialect
+func (Dialect) CreateMetaTableQuery(tableName string) string {
+	return fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			name VARCHAR(64) PRIMARY KEY,
+			value VARCHAR(255) NOT NULL
+		);
+	`, tableName)
+}
+
+// TableExistsQuery implements migration.Dialect
+func (Dialect) TableExistsQuery() string {
+	return "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = ?"
+}
+
+// ColumnsQuery implements migration.Dialect
+func (Dialect) ColumnsQuery() string {
+	return "SELECT column_name FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = ?"
+}
+
+// TimestampType implements migration.Dialect
+func (Dialect) TimestampType() string { return "TIMESTAMPTZ" }
+
+// AddColumnQuery implements migration.Dialect
+func (Dialect) AddColumnQuery(tableName, column, definition string) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", tableName, column, definition)
+}
+
+// Insert implements migration.Dialect, PostgreSQL does not support
+// LastInsertId so the id is retrieved using RETURNING
+func (Dialect) Insert(ctx context.Context, connection migration.Queryer, tableName string, columns []string, values []interface{}) (int64, error) {
+	var id int64
+	err := connection.QueryRowContext(ctx, db.Rebind(db.DriverPostgreSQL, fmt.Sprintf(
+		"INSERT INTO %s (%s) VALUES (%s) RETURNING id", tableName, strings.Join(columns, ", "), migration.Placeholders(len(values)),
+	)), values...).Scan(&id)
+	return id, err
+}
+
+// Split implements migration.Dialect using migration.SplitStatements
+func (Dialect) Split(script string) []string {
+	return migration.SplitStatements(db.DriverPostgreSQL, script)
+}
+
+// TransactionalDDL implements migration.Dialect
+func (Dialect) TransactionalDDL() bool { return true }
+
+// Lock implements migration.Dialect using pg_advisory_lock, the query is
+// cancelled if the lock is not acquired within the :timeout parameter
+func (Dialect) Lock(ctx context.Context, connection *sql.Conn, name string, timeout time.Duration) error {
+	lockCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	_, err := connection.ExecContext(lockCtx, "SELECT pg_advisory_lock($1)", lockKey(name))
+	if err != nil && lockCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+		return migration.ErrLockTimeout
+	}
+	return err
+}
+
+// Unlock implements migration.Dialect using pg_advisory_unlock
+func (Dialect) Unlock(ctx context.Context, connection *sql.Conn, name string) error {
+	_, err := connection.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", lockKey(name))
+	return err
+}
+
+// lockKey returns the advisory lock key for the lock named :name
+func lockKey(name string) int64 {
+	hash := fnv.New64a()
+	hash.Write([]byte(name))
+	return int64(hash.Sum64())
+}
@@ -0,0 +1,47 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/suite"
+	"github.com/usvc/go-db/migration"
+)
+
+type DialectTests struct {
+	suite.Suite
+}
+
+func TestDialect(t *testing.T) {
+	suite.Run(t, &DialectTests{})
+}
+
+func (s *DialectTests) TestLock() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	mock.ExpectExec("SELECT pg_advisory_lock\\(\\$1\\)").WithArgs(lockKey("migrations_lock")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("SELECT pg_advisory_unlock\\(\\$1\\)").WithArgs(lockKey("migrations_lock")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("SELECT pg_advisory_lock\\(\\$1\\)").WillDelayFor(time.Second).WillReturnResult(sqlmock.NewResult(0, 0))
+	engine := NewEngine("migrations", connection)
+	unlock, err := engine.Lock(context.Background(), time.Second)
+	s.Nil(err)
+	s.Nil(unlock())
+	_, err = engine.Lock(context.Background(), 10*time.Millisecond)
+	s.Equal(migration.ErrLockTimeout, err)
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *DialectTests) Test_lockKey() {
+	s.Equal(lockKey("migrations_lock"), lockKey("migrations_lock"))
+	s.NotEqual(lockKey("migrations_lock"), lockKey("other_migrations_lock"))
+}
+
+func (s *DialectTests) TestSplit() {
+	s.Equal([]string{
+		"CREATE TABLE a (id INTEGER)",
+		"CREATE FUNCTION f() RETURNS INTEGER AS $$ SELECT 1; $$ LANGUAGE sql",
+	}, Dialect{}.Split("CREATE TABLE a (id INTEGER);\nCREATE FUNCTION f() RETURNS INTEGER AS $$ SELECT 1; $$ LANGUAGE sql;\n"))
+}
@@ -0,0 +1,41 @@
+package postgres
+
+import "github.com/usvc/go-db/migration"
+
+const (
+	MigrationExtension = migration.MigrationExtension
+)
+
+type Migrations []*Migration
+
+// Len implements the sort.Interface
+func (m Migrations) Len() int { return len(m) }
+
+// Swap implements the sort.Interface
+func (m Migrations) Swap(i, j int) { m[i], m[j] = m[j], m[i] }
+
+// Less implements the sort.Interface, see migration.Migration.Before
+func (m Migrations) Less(i, j int) bool {
+	return (*migration.Migration)(m[i]).Before((*migration.Migration)(m[j]))
+}
+
+// Generic returns the migrations for use with the migration package
+func (m Migrations) Generic() migration.Migrations {
+	migrations := make(migration.Migrations, len(m))
+	for i := 0; i < len(m); i++ {
+		migrations[i] = (*migration.Migration)(m[i])
+	}
+	return migrations
+}
+
+// fromGeneric converts migrations from the migration package
+func fromGeneric(m migration.Migrations) Migrations {
+	if m == nil {
+		return nil
+	}
+	migrations := make(Migrations, len(m))
+	for i := 0; i < len(m); i++ {
+		migrations[i] = (*Migration)(m[i])
+	}
+	return migrations
+}
@@ -0,0 +1,20 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/usvc/go-db/migration"
+)
+
+// MigrationStatus describes the state of a migration, see
+// migration.MigrationStatus
+type MigrationStatus = migration.MigrationStatus
+
+// Status returns the status of each of the :migrations parameter and of
+// the migrations recorded in the migrations table named :tableName which
+// do not exist locally, migrations in progress for longer than
+// DefaultStaleAfter are stale (see Runner.Status)
+func Status(ctx context.Context, migrations Migrations, tableName string, connection *sql.DB) ([]MigrationStatus, error) {
+	return NewEngine(tableName, connection).Status(ctx, migrations.Generic())
+}
@@ -0,0 +1,80 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/usvc/go-db/migration"
+)
+
+func Init(tableName string, connection *sql.DB) error {
+	return NewEngine(tableName, connection).Init(context.Background())
+}
+
+// EnsureTable creates the migrations table named :tableName if it does not
+// exist and upgrades it otherwise, see migration.Engine.EnsureTable
+func EnsureTable(tableName string, connection *sql.DB) error {
+	return NewEngine(tableName, connection).EnsureTable(context.Background())
+}
+
+// NewEngine returns a migration.Engine using the PostgreSQL dialect
+func NewEngine(tableName string, connection *sql.DB) *migration.Engine {
+	return migration.NewEngine(Dialect{}, tableName, connection)
+}
+
+func GetMigrationNamesFromFilenames(filenameList []string) ([]string, []error) {
+	return migration.GetMigrationNamesFromFilenames(filenameList)
+}
+
+func New(name, up, down string) *Migration {
+	return (*Migration)(migration.New(name, up, down))
+}
+
+// NewFunc returns a migration which executes the :up and :down functions
+// instead of scripts, see migration.NewFunc
+func NewFunc(name string, up, down MigrationFunc) *Migration {
+	return (*Migration)(migration.NewFunc(name, up, down))
+}
+
+func NewFromDB(name, tableName string, connection *sql.DB) (*Migration, error) {
+	remoteMigration, err := NewEngine(tableName, connection).Load(context.Background(), name)
+	if err != nil {
+		return nil, err
+	}
+	return (*Migration)(remoteMigration), nil
+}
+
+func NewFromFile(name, upFilePath, downFilePath string) (*Migration, error) {
+	fileMigration, err := migration.NewFromFile(name, upFilePath, downFilePath)
+	if err != nil {
+		return nil, err
+	}
+	return (*Migration)(fileMigration), nil
+}
+
+func NewFromDirectory(directoryPath string) (Migrations, error) {
+	migrations, err := migration.NewFromDirectory(directoryPath)
+	return fromGeneric(migrations), err
+}
+
+// NewMigrationFiles creates the files of a migration named :name in the
+// directory :dir prefixed with a version according to the :scheme
+// parameter, see migration.NewMigrationFiles
+func NewMigrationFiles(dir, name, scheme string) (*Migration, error) {
+	createdMigration, err := migration.NewMigrationFiles(dir, name, scheme)
+	if err != nil {
+		return nil, err
+	}
+	return (*Migration)(createdMigration), nil
+}
+
+// NewFromSource loads the migrations in the directory :dir of the :source
+// parameter (see migration.Source)
+func NewFromSource(source migration.Source, dir string) (Migrations, error) {
+	migrations, err := migration.NewFromSource(source, dir)
+	return fromGeneric(migrations), err
+}
+
+func NormalizeQuery(query string) string {
+	return migration.NormalizeQuery(query)
+}
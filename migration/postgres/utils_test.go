@@ -0,0 +1,63 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/suite"
+)
+
+type UtilsTests struct {
+	suite.Suite
+}
+
+func TestUtils(t *testing.T) {
+	suite.Run(t, &UtilsTests{})
+}
+
+func (s *UtilsTests) TestGetMigrationNamesFromFilenames_error() {
+	migrationNames, err := GetMigrationNamesFromFilenames([]string{
+		"a.up.sql",
+		"b.up.sql",
+		"b.down.sql",
+		"randomFile",
+	})
+	s.EqualValues([]string{"b"}, migrationNames)
+	s.EqualValues([]error{
+		errors.New("could not find reverse migration for a.up.sql"),
+		errors.New("filename randomFile does not end with .{up, down}.sql"),
+	}, err)
+}
+
+func (s *UtilsTests) TestInit() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS migrations \\(\\s+id BIGSERIAL PRIMARY KEY").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("migrations_log").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS migrations_meta").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT value FROM migrations_meta WHERE name = \\$1").WithArgs("schema_version").
+		WillReturnRows(sqlmock.NewRows([]string{"value"}))
+	mock.ExpectQuery("(?i)SELECT column_name FROM information_schema.columns").WithArgs("migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("id").AddRow("checksum").AddRow("rolled_back_at").AddRow("kind").AddRow("started_at"))
+	mock.ExpectQuery("SELECT id, up, down FROM migrations WHERE checksum IS NULL").WillReturnRows(sqlmock.NewRows([]string{"id", "up", "down"}))
+	mock.ExpectExec("UPDATE migrations_meta SET value = \\$1 WHERE name = \\$2").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO migrations_meta").WithArgs("schema_version", "5").WillReturnResult(sqlmock.NewResult(1, 1))
+	s.Nil(Init("migrations", connection))
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *UtilsTests) TestNewEngine_tableExists() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	mock.ExpectQuery("table_schema = current_schema\\(\\) AND table_name = \\$1").
+		WithArgs("migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	exists, err := NewEngine("migrations", connection).TableExists(context.Background())
+	s.Nil(err)
+	s.True(exists)
+	s.Nil(mock.ExpectationsWereMet())
+}
@@ -0,0 +1,142 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Stale acquires the migration lock, which proves that no other process is
+// applying or rolling back migrations, and returns the recorded migrations
+// which have been in StatusApplying or StatusRollingBack without an error
+// for longer than StaleAfter, in the order they were recorded. These are
+// usually left behind by processes which died while migrating and can be
+// resolved with Repair
+func (r *Runner) Stale(ctx context.Context) (stale Migrations, err error) {
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if unlockErr := unlock(); unlockErr != nil && err == nil {
+			err = unlockErr
+		}
+	}()
+	history, err := r.Engine.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < len(history); i++ {
+		if isStale(history[i], r.staleAfter()) {
+			stale = append(stale, history[i])
+		}
+	}
+	return stale, nil
+}
+
+// Repair acquires the migration lock and performs the :action parameter
+// (one of the Repair* constants) on the recorded migration named :name,
+// which must either be stale (see Stale) or recorded as failed. Migrations
+// which have been in progress for less than StaleAfter are not repaired as
+// they may still be running on a process which does not use the migration
+// lock, see Engine.Repair. A *LogError is returned if the repair succeeded
+// but could not be recorded in the log table
+func (r *Runner) Repair(ctx context.Context, name string, action string) (err error) {
+	if !isRepairAction(action) {
+		return fmt.Errorf("[repair:%s] unknown repair action '%s'", name, action)
+	}
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if unlockErr := unlock(); unlockErr != nil && err == nil {
+			err = unlockErr
+		}
+	}()
+	remoteMigration, err := r.Engine.Load(ctx, name)
+	if err == sql.ErrNoRows {
+		return &StateError{Operation: "repair", Name: name}
+	} else if err != nil {
+		return fmt.Errorf("[repair:%s] failed to retrieve migration entry: '%s'", name, err)
+	}
+	failed := remoteMigration.Error != nil && len(*remoteMigration.Error) > 0
+	if !failed && isInProgress(remoteMigration.Status) && !isStale(remoteMigration, r.staleAfter()) {
+		return fmt.Errorf("[repair:%s] migration has been %s since %s which is less than %s ago, it may still be running", name, remoteMigration.Status, remoteMigration.StartedAt.Format(time.RFC3339), r.staleAfter())
+	}
+	return r.Engine.Repair(ctx, remoteMigration, action)
+}
+
+// staleAfter returns the StaleAfter duration of the runner or its default
+func (r *Runner) staleAfter() time.Duration {
+	if r.StaleAfter <= 0 {
+		return DefaultStaleAfter
+	}
+	return r.StaleAfter
+}
+
+// Repair performs the :action parameter (one of the Repair* constants) on
+// the row of the :m parameter, which must be in StatusApplying or
+// StatusRollingBack or have been recorded as failed, otherwise a
+// *StateError is returned:
+//
+//   - RepairApplied marks the migration as applied and clears its error, a
+//     migration which was being rolled back keeps its original applied_at
+//   - RepairFailed records an error for a migration which is in progress so
+//     that Validate reports it as failed, failed migrations are left as is
+//   - RepairRemove deletes the row as Resolve does
+//
+// It does not verify that the migration is no longer being processed, see
+// Runner.Repair. The repair is recorded in the log table
+func (e *Engine) Repair(ctx context.Context, m *Migration, action string) error {
+	started := time.Now()
+	return e.log(OperationRepair, m, started, e.repair(ctx, m, action))
+}
+
+func (e *Engine) repair(ctx context.Context, m *Migration, action string) error {
+	if !isRepairAction(action) {
+		return fmt.Errorf("[repair:%s] unknown repair action '%s'", m.Name, action)
+	}
+	remoteMigration, err := e.Load(ctx, m.Name)
+	if err == sql.ErrNoRows {
+		return &StateError{Operation: "repair", Name: m.Name}
+	} else if err != nil {
+		return fmt.Errorf("[repair:%s] failed to retrieve migration entry: '%s'", m.Name, err)
+	}
+	failed := remoteMigration.Error != nil && len(*remoteMigration.Error) > 0
+	if !failed && !isInProgress(remoteMigration.Status) {
+		return &StateError{Operation: "repair", Name: m.Name, Status: remoteMigration.Status}
+	}
+	switch action {
+	case RepairApplied:
+		_, err = e.Connection.ExecContext(ctx, e.query("UPDATE %s SET status = ?, applied_at = COALESCE(applied_at, ?), error = NULL WHERE id = ?"), StatusApplied, time.Now(), remoteMigration.ID)
+	case RepairFailed:
+		if failed {
+			return nil
+		}
+		_, err = e.Connection.ExecContext(ctx, e.query("UPDATE %s SET error = ? WHERE id = ?"), fmt.Sprintf("marked as failed by repair while %s", remoteMigration.Status), remoteMigration.ID)
+	case RepairRemove:
+		err = e.delete(ctx, e.Connection, remoteMigration)
+	}
+	if err != nil {
+		return fmt.Errorf("[repair:%s] failed to repair migration as '%s': '%s'", m.Name, action, err)
+	}
+	return nil
+}
+
+// isRepairAction returns true if the :action parameter is one of the
+// Repair* constants
+func isRepairAction(action string) bool {
+	return action == RepairApplied || action == RepairFailed || action == RepairRemove
+}
+
+// isStale returns true if the :m parameter has been in progress without an
+// error for at least the :threshold parameter, rows recorded before
+// started_at was introduced are always stale
+func isStale(m *Migration, threshold time.Duration) bool {
+	if !isInProgress(m.Status) || (m.Error != nil && len(*m.Error) > 0) {
+		return false
+	}
+	return m.StartedAt == nil || time.Since(*m.StartedAt) >= threshold
+}
@@ -0,0 +1,135 @@
+package migration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/suite"
+)
+
+type RepairTests struct {
+	suite.Suite
+}
+
+func TestRepair(t *testing.T) {
+	suite.Run(t, &RepairTests{})
+}
+
+var repairColumns = []string{"id", "name", "up", "down", "checksum", "error", "status", "applied_at", "created_at", "rolled_back_at", "kind", "started_at"}
+
+func (s *RepairTests) expectLoad(mock sqlmock.Sqlmock, name, status string, errorText interface{}, startedAt time.Time) {
+	mock.ExpectQuery("SELECT (.+) FROM migrations").WithArgs(name).WillReturnRows(sqlmock.NewRows(repairColumns).
+		AddRow(1, name, "UP", "DOWN", nil, errorText, status, nil, startedAt, nil, KindSQL, startedAt))
+}
+
+func (s *RepairTests) TestStale() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	now := time.Now()
+	mock.ExpectQuery("SELECT LOCK").WillReturnRows(sqlmock.NewRows([]string{"lock"}).AddRow(1))
+	mock.ExpectQuery("SELECT (.+) FROM migrations ORDER BY id").WillReturnRows(sqlmock.NewRows(repairColumns).
+		AddRow(1, "1_a", "UP", "DOWN", nil, nil, StatusApplied, now, now, nil, KindSQL, now.Add(-time.Hour)).
+		AddRow(2, "2_b", "UP", "DOWN", nil, nil, StatusApplying, nil, now, nil, KindSQL, now.Add(-time.Hour)).
+		AddRow(3, "3_c", "UP", "DOWN", nil, nil, StatusRollingBack, now, now, nil, KindSQL, now).
+		AddRow(4, "4_d", "UP", "DOWN", nil, "this is expected", StatusApplying, nil, now, nil, KindSQL, now.Add(-time.Hour)).
+		AddRow(5, "5_e", "UP", "DOWN", nil, nil, StatusRollingBack, now, now, nil, KindSQL, nil))
+	mock.ExpectExec("SELECT UNLOCK").WillReturnResult(sqlmock.NewResult(0, 0))
+	runner := NewRunner(NewEngine(testDialect{}, "migrations", connection), nil)
+	runner.StaleAfter = time.Minute
+	stale, err := runner.Stale(context.Background())
+	s.Nil(err)
+	s.Len(stale, 2)
+	s.Equal("2_b", stale[0].Name)
+	s.Equal("5_e", stale[1].Name)
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *RepairTests) TestRepair_applied() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	startedAt := time.Now().Add(-2 * DefaultStaleAfter)
+	mock.ExpectQuery("SELECT LOCK").WillReturnRows(sqlmock.NewRows([]string{"lock"}).AddRow(1))
+	s.expectLoad(mock, "a", StatusApplying, nil, startedAt)
+	s.expectLoad(mock, "a", StatusApplying, nil, startedAt)
+	mock.ExpectExec("UPDATE migrations SET status = \\$1, applied_at = COALESCE\\(applied_at, \\$2\\), error = NULL WHERE id = \\$3").
+		WithArgs(StatusApplied, sqlmock.AnyArg(), 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectLog(mock, "a", OperationRepair, OutcomeSucceeded)
+	mock.ExpectExec("SELECT UNLOCK").WillReturnResult(sqlmock.NewResult(0, 0))
+	s.Nil(NewRunner(NewEngine(testDialect{}, "migrations", connection), nil).Repair(context.Background(), "a", RepairApplied))
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *RepairTests) TestRepair_failed() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	s.expectLoad(mock, "a", StatusRollingBack, nil, time.Now())
+	mock.ExpectExec("UPDATE migrations SET error = \\$1 WHERE id = \\$2").
+		WithArgs("marked as failed by repair while rolling back", 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectLog(mock, "a", OperationRepair, OutcomeSucceeded)
+	s.Nil(NewEngine(testDialect{}, "migrations", connection).Repair(context.Background(), New("a", "", ""), RepairFailed))
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *RepairTests) TestRepair_remove() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	mock.ExpectQuery("SELECT LOCK").WillReturnRows(sqlmock.NewRows([]string{"lock"}).AddRow(1))
+	s.expectLoad(mock, "a", StatusApplying, "this is expected", time.Now())
+	s.expectLoad(mock, "a", StatusApplying, "this is expected", time.Now())
+	mock.ExpectExec("DELETE FROM migrations WHERE name = \\$1").WithArgs("a").WillReturnResult(sqlmock.NewResult(0, 1))
+	expectLog(mock, "a", OperationRepair, OutcomeSucceeded)
+	mock.ExpectExec("SELECT UNLOCK").WillReturnResult(sqlmock.NewResult(0, 0))
+	s.Nil(NewRunner(NewEngine(testDialect{}, "migrations", connection), nil).Repair(context.Background(), "a", RepairRemove))
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *RepairTests) TestRepair_error_running() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	mock.ExpectQuery("SELECT LOCK").WillReturnRows(sqlmock.NewRows([]string{"lock"}).AddRow(1))
+	s.expectLoad(mock, "a", StatusApplying, nil, time.Now())
+	mock.ExpectExec("SELECT UNLOCK").WillReturnResult(sqlmock.NewResult(0, 0))
+	err = NewRunner(NewEngine(testDialect{}, "migrations", connection), nil).Repair(context.Background(), "a", RepairApplied)
+	s.Contains(err.Error(), "[repair:a] migration has been applying since")
+	s.Contains(err.Error(), "it may still be running")
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *RepairTests) TestRepair_error_state() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	s.expectLoad(mock, "a", StatusApplied, nil, time.Now())
+	expectLog(mock, "a", OperationRepair, OutcomeFailed)
+	err = NewEngine(testDialect{}, "migrations", connection).Repair(context.Background(), New("a", "", ""), RepairRemove)
+	s.Equal(&StateError{Operation: "repair", Name: "a", Status: StatusApplied}, err)
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *RepairTests) TestRepair_error_action() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	err = NewRunner(NewEngine(testDialect{}, "migrations", connection), nil).Repair(context.Background(), "a", "ignore")
+	s.Equal("[repair:a] unknown repair action 'ignore'", err.Error())
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *RepairTests) TestValidate_inProgress() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	s.expectLoad(mock, "a", StatusApplying, nil, time.Now())
+	err = NewEngine(testDialect{}, "migrations", connection).Validate(context.Background(), New("a", "UP", "DOWN"))
+	s.Equal(&StateError{Operation: "validate", Name: "a", Status: StatusApplying}, err)
+	s.Nil(mock.ExpectationsWereMet())
+}
@@ -0,0 +1,333 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Runner applies a set of migrations in order, stopping at the first
+// migration which fails
+type Runner struct {
+	// Engine is the engine used to apply the migrations
+	Engine *Engine
+	// Migrations contains the migrations to apply, these are sorted before
+	// being applied
+	Migrations Migrations
+	// ResolveFailed causes migrations that have been recorded as failed to be
+	// resolved and applied again instead of halting the run
+	ResolveFailed bool
+	// AllowOutOfOrder allows pending migrations with a version lower than
+	// the latest applied version to be applied instead of halting the run
+	AllowOutOfOrder bool
+	// LockTimeout is the maximum duration to wait for other processes to
+	// finish migrating, defaults to DefaultLockTimeout
+	LockTimeout time.Duration
+	// StaleAfter is the duration after which a migration which is still
+	// being applied or rolled back is considered stale by Stale and Repair,
+	// defaults to DefaultStaleAfter
+	StaleAfter time.Duration
+}
+
+// Report describes the outcome of a run
+type Report struct {
+	// Applied contains the migrations that were applied in this run
+	Applied Migrations `json:"applied" yaml:"applied"`
+	// RolledBack contains the migrations that were rolled back in this run,
+	// newest first
+	RolledBack Migrations `json:"rolled_back" yaml:"rolled_back"`
+	// Baselined contains the migrations that were recorded as
+	// StatusBaselined in this run, see Runner.Baseline
+	Baselined Migrations `json:"baselined" yaml:"baselined"`
+	// Skipped contains the migrations that had already been applied
+	Skipped Migrations `json:"skipped" yaml:"skipped"`
+	// Failed contains the migration that failed, if any
+	Failed *Migration `json:"failed" yaml:"failed"`
+	// Pending contains the migrations that were not attempted because of a
+	// failure or cancellation, in the order they would have been attempted
+	Pending Migrations `json:"pending" yaml:"pending"`
+	// Error contains the error that halted the run, if any
+	Error error `json:"-" yaml:"-"`
+	// Warnings contains the errors which did not halt the run, such as a
+	// *LogError for a migration which was processed but not logged
+	Warnings []error `json:"-" yaml:"-"`
+}
+
+// succeeded returns true if the :err parameter is nil or a *LogError, which
+// is added to the Warnings of the report as the operation succeeded
+func (r *Report) succeeded(err error) bool {
+	if isLogError(err) {
+		r.Warnings = append(r.Warnings, err)
+		return true
+	}
+	return err == nil
+}
+
+// NewRunner returns a Runner that applies the :migrations parameter using
+// the :engine parameter
+func NewRunner(engine *Engine, migrations Migrations) *Runner {
+	return &Runner{
+		Engine:     engine,
+		Migrations: migrations,
+	}
+}
+
+// Apply acquires the migration lock, ensures the migrations table exists,
+// validates the history of every migration and applies pending migrations
+// in order. Duplicate names or versions and pending migrations older than
+// the latest applied one (unless AllowOutOfOrder is set) halt the run
+// before anything is applied. The returned error is the same as
+// Report.Error
+func (r *Runner) Apply(ctx context.Context) (report *Report, err error) {
+	report = &Report{}
+	migrations := make(Migrations, len(r.Migrations))
+	copy(migrations, r.Migrations)
+	sort.Sort(migrations)
+	if err := migrations.Validate(); err != nil {
+		report.Pending = migrations
+		report.Error = err
+		return report, err
+	}
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		report.Pending = migrations
+		report.Error = err
+		return report, err
+	}
+	defer func() {
+		if unlockErr := unlock(); unlockErr != nil && err == nil {
+			report.Error = unlockErr
+			err = unlockErr
+		}
+	}()
+	if err := r.ensureTable(ctx); err != nil {
+		report.Pending = migrations
+		report.Error = err
+		return report, err
+	}
+	if !r.AllowOutOfOrder {
+		if err := r.checkOrder(ctx, migrations); err != nil {
+			report.Pending = migrations
+			report.Error = err
+			return report, err
+		}
+	}
+	for i := 0; i < len(migrations); i++ {
+		migration := migrations[i]
+		if err := ctx.Err(); err != nil {
+			report.Pending = migrations[i:]
+			report.Error = err
+			return report, err
+		}
+		applied, err := r.validate(ctx, report, migration)
+		if err == nil && !applied {
+			err = r.Engine.Apply(ctx, migration)
+			if err == NoErrAlreadyApplied {
+				applied, err = true, nil
+			}
+		}
+		if !report.succeeded(err) {
+			report.Failed = migration
+			report.Pending = migrations[i+1:]
+			report.Error = err
+			return report, err
+		}
+		if applied {
+			report.Skipped = append(report.Skipped, migration)
+		} else {
+			report.Applied = append(report.Applied, migration)
+		}
+	}
+	return report, nil
+}
+
+// RollbackTo rolls back the applied migrations which were applied after
+// the migration named :name, newest first, leaving :name applied. The
+// returned error is the same as Report.Error
+func (r *Runner) RollbackTo(ctx context.Context, name string) (*Report, error) {
+	return r.rollback(ctx, func(applied Migrations) (int, error) {
+		for i := 0; i < len(applied); i++ {
+			if applied[i].Name == name {
+				return i, nil
+			}
+		}
+		return 0, fmt.Errorf("failed to find applied migration '%s' to roll back to", name)
+	})
+}
+
+// RollbackSteps rolls back the last :steps applied migrations, newest
+// first. The returned error is the same as Report.Error
+func (r *Runner) RollbackSteps(ctx context.Context, steps int) (*Report, error) {
+	return r.rollback(ctx, func(applied Migrations) (int, error) {
+		if steps < 1 {
+			return 0, fmt.Errorf("failed to roll back %v steps: steps must be at least 1", steps)
+		} else if steps > len(applied) {
+			return len(applied), nil
+		}
+		return steps, nil
+	})
+}
+
+// rollback acquires the migration lock, ensures the migrations table
+// exists and rolls back the number of applied migrations returned by the
+// :count parameter, newest first, using the downward scripts of Migrations
+// or the recorded ones if the migration does not exist locally
+func (r *Runner) rollback(ctx context.Context, count func(applied Migrations) (int, error)) (report *Report, err error) {
+	report = &Report{}
+	unlock, err := r.lock(ctx)
+	if err != nil {
+		report.Error = err
+		return report, err
+	}
+	defer func() {
+		if unlockErr := unlock(); unlockErr != nil && err == nil {
+			report.Error = unlockErr
+			err = unlockErr
+		}
+	}()
+	if err := r.ensureTable(ctx); err != nil {
+		report.Error = err
+		return report, err
+	}
+	applied, err := r.applied(ctx)
+	if err != nil {
+		report.Error = err
+		return report, err
+	}
+	steps, err := count(applied)
+	if err != nil {
+		report.Error = err
+		return report, err
+	}
+	migrations := applied[:steps]
+	for i := 0; i < len(migrations); i++ {
+		migration := migrations[i]
+		if err := ctx.Err(); err != nil {
+			report.Pending = migrations[i:]
+			report.Error = err
+			return report, err
+		}
+		if err := r.Engine.Rollback(ctx, migration); !report.succeeded(err) {
+			report.Failed = migration
+			report.Pending = migrations[i+1:]
+			report.Error = err
+			return report, err
+		}
+		report.RolledBack = append(report.RolledBack, migration)
+	}
+	return report, nil
+}
+
+// applied returns the applied migrations ordered by the time they were
+// applied, newest first, local migrations are returned in place of their
+// recorded versions
+func (r *Runner) applied(ctx context.Context) (Migrations, error) {
+	history, err := r.Engine.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve migration history: '%s'", err)
+	}
+	localMigrations := map[string]*Migration{}
+	for i := 0; i < len(r.Migrations); i++ {
+		localMigrations[r.Migrations[i].Name] = r.Migrations[i]
+	}
+	var applied Migrations
+	for i := 0; i < len(history); i++ {
+		if !isApplied(history[i].Status) {
+			continue
+		}
+		migration := history[i]
+		if localMigration, ok := localMigrations[migration.Name]; ok {
+			copied := *localMigration
+			copied.ID = migration.ID
+			copied.AppliedAt = migration.AppliedAt
+			migration = &copied
+		}
+		applied = append(applied, migration)
+	}
+	sort.SliceStable(applied, func(i, j int) bool {
+		if applied[i].AppliedAt != nil && applied[j].AppliedAt != nil && !applied[i].AppliedAt.Equal(*applied[j].AppliedAt) {
+			return applied[i].AppliedAt.After(*applied[j].AppliedAt)
+		}
+		return applied[i].ID > applied[j].ID
+	})
+	return applied, nil
+}
+
+// checkOrder checks the order of the :migrations parameter against the
+// migrations recorded in the migrations table, see checkOrder
+func (r *Runner) checkOrder(ctx context.Context, migrations Migrations) error {
+	history, err := r.Engine.List(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve migration history: '%s'", err)
+	}
+	return checkOrder(migrations, history)
+}
+
+// checkOrder returns an error if a migration of the :migrations parameter
+// is not in the :history parameter but has a lower version than the latest
+// migration in it
+func checkOrder(migrations, history Migrations) error {
+	var latest *Migration
+	recorded := map[string]bool{}
+	for i := 0; i < len(history); i++ {
+		if history[i].Status == StatusRolledBack {
+			continue
+		}
+		recorded[history[i].Name] = true
+		if _, versioned := history[i].Version(); versioned && (latest == nil || latest.Before(history[i])) {
+			latest = history[i]
+		}
+	}
+	if latest == nil {
+		return nil
+	}
+	latestVersion, _ := latest.Version()
+	for i := 0; i < len(migrations); i++ {
+		version, versioned := migrations[i].Version()
+		if versioned && version < latestVersion && !recorded[migrations[i].Name] {
+			return fmt.Errorf("migration '%s' (version %v) has not been applied but is older than the applied migration '%s' (version %v), renumber it above %v or set AllowOutOfOrder to apply it anyway", migrations[i].Name, version, latest.Name, latestVersion, latestVersion)
+		}
+	}
+	return nil
+}
+
+// ensureTable creates the migrations table if it does not exist and
+// upgrades it otherwise, see Engine.EnsureTable
+func (r *Runner) ensureTable(ctx context.Context) error {
+	if err := r.Engine.EnsureTable(ctx); err != nil {
+		return fmt.Errorf("failed to ensure migrations table '%s': '%s'", r.Engine.TableName, err)
+	}
+	return nil
+}
+
+// lock acquires the migration lock of the engine
+func (r *Runner) lock(ctx context.Context) (func() error, error) {
+	timeout := r.LockTimeout
+	if timeout <= 0 {
+		timeout = DefaultLockTimeout
+	}
+	return r.Engine.Lock(ctx, timeout)
+}
+
+// validate returns true if the :migration parameter has already been
+// applied, resolving it first if it has been recorded as failed and
+// ResolveFailed is set, warnings are added to the :report parameter
+func (r *Runner) validate(ctx context.Context, report *Report, migration *Migration) (bool, error) {
+	err := r.Engine.Validate(ctx, migration)
+	if err == nil {
+		return true, nil
+	} else if err == NoErrDoesNotExist {
+		return false, nil
+	} else if !r.ResolveFailed {
+		return false, err
+	}
+	remoteMigration, remoteErr := r.Engine.Load(ctx, migration.Name)
+	if remoteErr != nil || remoteMigration.Error == nil {
+		return false, err
+	}
+	if err := r.Engine.Resolve(ctx, migration); !report.succeeded(err) {
+		return false, err
+	}
+	return false, nil
+}
@@ -0,0 +1,280 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/suite"
+)
+
+type RunnerTests struct {
+	suite.Suite
+}
+
+func TestRunner(t *testing.T) {
+	suite.Run(t, &RunnerTests{})
+}
+
+func (s *RunnerTests) expectLock(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery("SELECT LOCK").WithArgs("migrations_lock", DefaultLockTimeout.Seconds()).
+		WillReturnRows(sqlmock.NewRows([]string{"lock"}).AddRow(1))
+}
+
+func (s *RunnerTests) expectUnlock(mock sqlmock.Sqlmock) {
+	mock.ExpectExec("SELECT UNLOCK").WithArgs("migrations_lock").WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func (s *RunnerTests) expectHistory(mock sqlmock.Sqlmock, names ...string) {
+	rows := sqlmock.NewRows([]string{"id", "name", "up", "down", "checksum", "error", "status", "applied_at", "created_at", "rolled_back_at", "kind", "started_at"})
+	for i := 0; i < len(names); i++ {
+		rows.AddRow(i+1, names[i], "UP", "DOWN", nil, nil, StatusApplied, time.Now(), time.Now(), nil, KindSQL, nil)
+	}
+	mock.ExpectQuery("SELECT (.+) FROM migrations ORDER BY id").WillReturnRows(rows)
+}
+
+func (s *RunnerTests) expectNotFound(mock sqlmock.Sqlmock, name string) {
+	mock.ExpectQuery("SELECT (.+) FROM migrations").WithArgs(name).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+}
+
+func (s *RunnerTests) TestApply() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	s.expectLock(mock)
+	expectEnsureTable(mock)
+	s.expectHistory(mock)
+	s.expectNotFound(mock, "a")
+	s.expectNotFound(mock, "a")
+	mock.ExpectQuery("INSERT INTO migrations").WithArgs("a", "UP a", "DOWN a", sqlmock.AnyArg(), StatusApplying, KindSQL, sqlmock.AnyArg()).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec("UP a").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE migrations SET applied_at").WillReturnResult(sqlmock.NewResult(0, 1))
+	expectLog(mock, "a", OperationApply, OutcomeSucceeded)
+	s.expectNotFound(mock, "b")
+	s.expectNotFound(mock, "b")
+	mock.ExpectQuery("INSERT INTO migrations").WithArgs("b", "UP b", "DOWN b", sqlmock.AnyArg(), StatusApplying, KindSQL, sqlmock.AnyArg()).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	mock.ExpectExec("UP b").WillReturnError(fmt.Errorf("this is expected"))
+	mock.ExpectExec("UPDATE migrations SET error").WillReturnResult(sqlmock.NewResult(0, 1))
+	expectLog(mock, "b", OperationApply, OutcomeFailed)
+	s.expectUnlock(mock)
+	migrations := Migrations{
+		New("c", "UP c", "DOWN c"),
+		New("b", "UP b", "DOWN b"),
+		New("a", "UP a", "DOWN a"),
+	}
+	engine := NewEngine(testDialect{}, "migrations", connection)
+	report, err := NewRunner(engine, migrations).Apply(context.Background())
+	s.NotNil(err)
+	s.Contains(err.Error(), "this is expected")
+	s.Equal(err, report.Error)
+	s.Len(report.Applied, 1)
+	s.Equal("a", report.Applied[0].Name)
+	s.Equal("b", report.Failed.Name)
+	s.Len(report.Pending, 1)
+	s.Equal("c", report.Pending[0].Name)
+	s.Equal("c", migrations[0].Name)
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *RunnerTests) TestApply_logFailed() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	s.expectLock(mock)
+	expectEnsureTable(mock)
+	s.expectHistory(mock)
+	s.expectNotFound(mock, "a")
+	s.expectNotFound(mock, "a")
+	mock.ExpectQuery("INSERT INTO migrations").WithArgs("a", "UP a", "DOWN a", sqlmock.AnyArg(), StatusApplying, KindSQL, sqlmock.AnyArg()).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec("UP a").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE migrations SET applied_at").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO migrations_log").WillReturnError(fmt.Errorf("this is expected"))
+	s.expectNotFound(mock, "b")
+	s.expectNotFound(mock, "b")
+	mock.ExpectQuery("INSERT INTO migrations").WithArgs("b", "UP b", "DOWN b", sqlmock.AnyArg(), StatusApplying, KindSQL, sqlmock.AnyArg()).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	mock.ExpectExec("UP b").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE migrations SET applied_at").WillReturnResult(sqlmock.NewResult(0, 1))
+	expectLog(mock, "b", OperationApply, OutcomeSucceeded)
+	s.expectUnlock(mock)
+	engine := NewEngine(testDialect{}, "migrations", connection)
+	report, err := NewRunner(engine, Migrations{New("b", "UP b", "DOWN b"), New("a", "UP a", "DOWN a")}).Apply(context.Background())
+	s.Nil(err)
+	s.Nil(report.Failed)
+	s.Len(report.Applied, 2)
+	s.Len(report.Warnings, 1)
+	s.IsType(&LogError{}, report.Warnings[0])
+	s.Contains(report.Warnings[0].Error(), "[apply:a] failed to record operation in log table 'migrations_log': 'this is expected'")
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *RunnerTests) TestApply_resolveFailed() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	columns := []string{"id", "name", "up", "down", "checksum", "error", "status", "applied_at", "created_at", "rolled_back_at", "kind", "started_at"}
+	failedRow := sqlmock.NewRows(columns).AddRow(1, "a", "UP a", "DOWN a", nil, "this is expected", StatusApplying, nil, nil, nil, KindSQL, nil)
+	s.expectLock(mock)
+	expectEnsureTable(mock)
+	s.expectHistory(mock)
+	mock.ExpectQuery("SELECT (.+) FROM migrations").WithArgs("a").WillReturnRows(failedRow)
+	mock.ExpectQuery("SELECT (.+) FROM migrations").WithArgs("a").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "a", "UP a", "DOWN a", nil, "this is expected", StatusApplying, nil, nil, nil, KindSQL, nil))
+	mock.ExpectExec("DELETE FROM migrations WHERE name = \\$1").WithArgs("a").WillReturnResult(sqlmock.NewResult(0, 1))
+	expectLog(mock, "a", OperationResolve, OutcomeSucceeded)
+	s.expectNotFound(mock, "a")
+	mock.ExpectQuery("INSERT INTO migrations").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	mock.ExpectExec("UP a").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE migrations SET applied_at").WillReturnResult(sqlmock.NewResult(0, 1))
+	expectLog(mock, "a", OperationApply, OutcomeSucceeded)
+	s.expectUnlock(mock)
+	runner := NewRunner(NewEngine(testDialect{}, "migrations", connection), Migrations{New("a", "UP a", "DOWN a")})
+	runner.ResolveFailed = true
+	report, err := runner.Apply(context.Background())
+	s.Nil(err)
+	s.Len(report.Applied, 1)
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *RunnerTests) TestApply_outOfOrder() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	s.expectLock(mock)
+	expectEnsureTable(mock)
+	s.expectHistory(mock, "1_a", "10_c")
+	s.expectUnlock(mock)
+	s.expectLock(mock)
+	expectEnsureTable(mock)
+	for _, name := range []string{"1_a", "9_b", "10_c"} {
+		mock.ExpectQuery("SELECT (.+) FROM migrations").WithArgs(name).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "up", "down", "checksum", "error", "status", "applied_at", "created_at", "rolled_back_at", "kind", "started_at"}).AddRow(1, name, "UP", "DOWN", nil, nil, StatusApplied, nil, nil, nil, KindSQL, nil))
+	}
+	s.expectUnlock(mock)
+	migrations := Migrations{New("10_c", "UP", "DOWN"), New("9_b", "UP", "DOWN"), New("1_a", "UP", "DOWN")}
+	runner := NewRunner(NewEngine(testDialect{}, "migrations", connection), migrations)
+	report, err := runner.Apply(context.Background())
+	s.Contains(err.Error(), "migration '9_b' (version 9) has not been applied but is older than the applied migration '10_c' (version 10)")
+	s.Len(report.Pending, 3)
+	runner.AllowOutOfOrder = true
+	report, err = runner.Apply(context.Background())
+	s.Nil(err)
+	s.Len(report.Skipped, 3)
+	s.Equal("9_b", report.Skipped[1].Name)
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *RunnerTests) TestApply_duplicateVersion() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	migrations := Migrations{New("1_a", "UP", "DOWN"), New("01_b", "UP", "DOWN")}
+	report, err := NewRunner(NewEngine(testDialect{}, "migrations", connection), migrations).Apply(context.Background())
+	s.Contains(err.Error(), "duplicate migration version 1 used by '01_b'")
+	s.Len(report.Pending, 2)
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *RunnerTests) expectApplied(mock sqlmock.Sqlmock, names ...string) {
+	expectEnsureTable(mock)
+	rows := sqlmock.NewRows([]string{"id", "name", "up", "down", "checksum", "error", "status", "applied_at", "created_at", "rolled_back_at", "kind", "started_at"})
+	appliedAt := time.Now()
+	for i := 0; i < len(names); i++ {
+		rows.AddRow(i+1, names[i], "UP "+names[i], "DOWN "+names[i], nil, nil, StatusApplied, appliedAt, appliedAt, nil, KindSQL, nil)
+	}
+	rows.AddRow(len(names)+1, "failed", "UP", "DOWN", nil, "this is expected", StatusApplying, nil, appliedAt, nil, KindSQL, nil)
+	mock.ExpectQuery("SELECT (.+) FROM migrations ORDER BY id").WillReturnRows(rows)
+}
+
+func (s *RunnerTests) expectRollback(mock sqlmock.Sqlmock, name, down string) {
+	mock.ExpectQuery("SELECT id, status FROM migrations").WithArgs(name).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status"}).AddRow(1, StatusApplied))
+	mock.ExpectExec("UPDATE migrations SET status = \\$1, started_at = \\$2 WHERE id = \\$3").WithArgs(StatusRollingBack, sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(down).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE migrations SET status = \\$1, rolled_back_at = \\$2 WHERE id = \\$3").WithArgs(StatusRolledBack, sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	expectLog(mock, name, OperationRollback, OutcomeSucceeded)
+}
+
+func (s *RunnerTests) TestRollbackTo() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	s.expectLock(mock)
+	s.expectApplied(mock, "1_a", "2_b", "3_c")
+	s.expectRollback(mock, "3_c", "DOWN 3_c")
+	s.expectRollback(mock, "2_b", "LOCAL DOWN 2_b")
+	s.expectUnlock(mock)
+	runner := NewRunner(NewEngine(testDialect{}, "migrations", connection), Migrations{New("2_b", "UP 2_b", "LOCAL DOWN 2_b")})
+	report, err := runner.RollbackTo(context.Background(), "1_a")
+	s.Nil(err)
+	s.Len(report.RolledBack, 2)
+	s.Equal("3_c", report.RolledBack[0].Name)
+	s.Equal("2_b", report.RolledBack[1].Name)
+	s.Equal(int64(0), runner.Migrations[0].ID)
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *RunnerTests) TestRollbackTo_notApplied() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	s.expectLock(mock)
+	s.expectApplied(mock, "1_a")
+	s.expectUnlock(mock)
+	report, err := NewRunner(NewEngine(testDialect{}, "migrations", connection), nil).RollbackTo(context.Background(), "failed")
+	s.Contains(err.Error(), "failed to find applied migration 'failed'")
+	s.Empty(report.RolledBack)
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *RunnerTests) TestRollbackSteps() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	s.expectLock(mock)
+	s.expectApplied(mock, "1_a", "2_b", "3_c")
+	s.expectRollback(mock, "3_c", "DOWN 3_c")
+	mock.ExpectQuery("SELECT id, status FROM migrations").WithArgs("2_b").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status"}).AddRow(2, StatusApplied))
+	mock.ExpectExec("UPDATE migrations SET status").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DOWN 2_b").WillReturnError(fmt.Errorf("this is expected"))
+	mock.ExpectExec("UPDATE migrations SET error").WillReturnResult(sqlmock.NewResult(0, 1))
+	expectLog(mock, "2_b", OperationRollback, OutcomeFailed)
+	s.expectUnlock(mock)
+	report, err := NewRunner(NewEngine(testDialect{}, "migrations", connection), nil).RollbackSteps(context.Background(), 5)
+	s.Contains(err.Error(), "this is expected")
+	s.Len(report.RolledBack, 1)
+	s.Equal("2_b", report.Failed.Name)
+	s.Len(report.Pending, 1)
+	s.Equal("1_a", report.Pending[0].Name)
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *RunnerTests) TestApply_cancelled() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	engine := NewEngine(testDialect{}, "migrations", connection)
+	report, err := NewRunner(engine, Migrations{New("a", "UP a", "DOWN a")}).Apply(ctx)
+	s.Contains(err.Error(), context.Canceled.Error())
+	s.Len(report.Pending, 1)
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *RunnerTests) TestApply_locked() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	mock.ExpectQuery("SELECT LOCK").WithArgs("migrations_lock", float64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"lock"}).AddRow(0))
+	runner := NewRunner(NewEngine(testDialect{}, "migrations", connection), Migrations{New("a", "UP a", "DOWN a")})
+	runner.LockTimeout = time.Second
+	report, err := runner.Apply(context.Background())
+	s.Equal(ErrLockTimeout, err)
+	s.Len(report.Pending, 1)
+	s.Nil(mock.ExpectationsWereMet())
+}
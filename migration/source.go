@@ -0,0 +1,200 @@
+package migration
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Source provides the migration files loaded by NewFromSource, names
+// passed to a Source are slash-separated paths
+type Source interface {
+	// ReadDir returns the entries of the directory named :dir
+	ReadDir(dir string) ([]os.FileInfo, error)
+	// ReadFile returns the contents of the file named :name
+	ReadFile(name string) ([]byte, error)
+}
+
+// OSSource is a Source which reads migration files from the host filesystem
+type OSSource struct{}
+
+// ReadDir implements Source
+func (OSSource) ReadDir(dir string) ([]os.FileInfo, error) { return ioutil.ReadDir(dir) }
+
+// ReadFile implements Source
+func (OSSource) ReadFile(name string) ([]byte, error) { return ioutil.ReadFile(name) }
+
+// HTTPSource is a Source which reads migration files from a
+// http.FileSystem (eg. one generated by an asset embedding tool)
+type HTTPSource struct {
+	FileSystem http.FileSystem
+}
+
+// ReadDir implements Source
+func (s HTTPSource) ReadDir(dir string) ([]os.FileInfo, error) {
+	directory, err := s.FileSystem.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer directory.Close()
+	entries, err := directory.Readdir(-1)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+// ReadFile implements Source
+func (s HTTPSource) ReadFile(name string) ([]byte, error) {
+	file, err := s.FileSystem.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ioutil.ReadAll(file)
+}
+
+// MapSource is an in-memory Source which maps slash-separated file paths
+// to their contents, mostly useful for tests
+type MapSource map[string]string
+
+// ReadDir implements Source
+func (s MapSource) ReadDir(dir string) ([]os.FileInfo, error) {
+	dir = path.Clean(dir)
+	entries := map[string]os.FileInfo{}
+	for name, contents := range s {
+		relativePath := strings.TrimPrefix(path.Clean(name), dir+"/")
+		if dir == "." {
+			relativePath = path.Clean(name)
+		} else if relativePath == path.Clean(name) {
+			continue
+		}
+		if index := strings.Index(relativePath, "/"); index != -1 {
+			entries[relativePath[:index]] = mapFileInfo{name: relativePath[:index], dir: true}
+			continue
+		}
+		entries[relativePath] = mapFileInfo{name: relativePath, size: int64(len(contents))}
+	}
+	if len(entries) == 0 {
+		return nil, &os.PathError{Op: "readdir", Path: dir, Err: os.ErrNotExist}
+	}
+	var fileInfos []os.FileInfo
+	for _, entry := range entries {
+		fileInfos = append(fileInfos, entry)
+	}
+	sort.Slice(fileInfos, func(i, j int) bool { return fileInfos[i].Name() < fileInfos[j].Name() })
+	return fileInfos, nil
+}
+
+// ReadFile implements Source
+func (s MapSource) ReadFile(name string) ([]byte, error) {
+	for filePath, contents := range s {
+		if path.Clean(filePath) == path.Clean(name) {
+			return []byte(contents), nil
+		}
+	}
+	return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+}
+
+// mapFileInfo implements os.FileInfo for entries of a MapSource
+type mapFileInfo struct {
+	name string
+	size int64
+	dir  bool
+}
+
+func (i mapFileInfo) Name() string       { return i.name }
+func (i mapFileInfo) Size() int64        { return i.size }
+func (i mapFileInfo) ModTime() time.Time { return time.Time{} }
+func (i mapFileInfo) IsDir() bool        { return i.dir }
+func (i mapFileInfo) Sys() interface{}   { return nil }
+func (i mapFileInfo) Mode() os.FileMode {
+	if i.dir {
+		return os.ModeDir | 0555
+	}
+	return 0444
+}
+
+// NewFromSource loads the migrations in the directory :dir of the :source
+// parameter and its subdirectories sorted in the order they should be
+// applied, migrations are named after their files
+// so names (and versions) must be unique across subdirectories. Errors for
+// files which could not be loaded are returned together with the
+// migrations which could
+func NewFromSource(source Source, dir string) (Migrations, error) {
+	migrations, errs, err := loadFromSource(source, dir)
+	if err != nil {
+		return nil, err
+	}
+	sort.Sort(migrations)
+	if err := migrations.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if errs != nil {
+		var errString strings.Builder
+		for i := 0; i < len(errs); i++ {
+			err = errs[i]
+			errString.WriteString("\n")
+			errString.WriteString(err.Error())
+		}
+		return migrations, fmt.Errorf("following errors/warnings happened: %s", errString.String())
+	}
+	return migrations, nil
+}
+
+// loadFromSource loads the migrations in the directory :dir of the :source
+// parameter and its subdirectories, the returned error is only set if
+// :dir could not be read
+func loadFromSource(source Source, dir string) (Migrations, []error, error) {
+	directoryListing, err := source.ReadDir(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	var migrations Migrations
+	var filenames []string
+	var errs []error
+	for i := 0; i < len(directoryListing); i++ {
+		file := directoryListing[i]
+		if !file.IsDir() {
+			filenames = append(filenames, file.Name())
+			continue
+		}
+		subdirectoryMigrations, subdirectoryErrs, err := loadFromSource(source, path.Join(dir, file.Name()))
+		if err != nil {
+			errs = append(errs, err)
+		}
+		migrations = append(migrations, subdirectoryMigrations...)
+		errs = append(errs, subdirectoryErrs...)
+	}
+	filenames, filenameErrs := GetMigrationNamesFromFilenames(filenames)
+	errs = append(errs, filenameErrs...)
+	for i := 0; i < len(filenames); i++ {
+		filename := filenames[i]
+		migration, err := newFromSourceFiles(source, filename, path.Join(dir, filename+".up.sql"), path.Join(dir, filename+".down.sql"))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		migration.Path = path.Join(dir, filename)
+		migrations = append(migrations, migration)
+	}
+	return migrations, errs, nil
+}
+
+func newFromSourceFiles(source Source, name, upFilePath, downFilePath string) (*Migration, error) {
+	upFileContents, err := source.ReadFile(upFilePath)
+	if err != nil {
+		return nil, err
+	}
+	downFileContents, err := source.ReadFile(downFilePath)
+	if err != nil {
+		return nil, err
+	}
+	return New(name, string(upFileContents), string(downFileContents)), nil
+}
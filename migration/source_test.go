@@ -0,0 +1,86 @@
+package migration
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type SourceTests struct {
+	suite.Suite
+}
+
+func TestSource(t *testing.T) {
+	suite.Run(t, &SourceTests{})
+}
+
+func (s *SourceTests) TestNewFromSource_map() {
+	migrations, err := NewFromSource(MapSource{
+		"migrations/2_b.up.sql":   "CREATE TABLE b (id INTEGER);",
+		"migrations/2_b.down.sql": "DROP TABLE b;",
+		"migrations/1_a.up.sql":   "CREATE TABLE a (id INTEGER);",
+		"migrations/1_a.down.sql": "DROP TABLE a;",
+		"other/3_c.up.sql":        "CREATE TABLE c (id INTEGER);",
+	}, "migrations")
+	s.Nil(err)
+	s.Len(migrations, 2)
+	s.Equal("1_a", migrations[0].Name)
+	s.Equal("CREATE TABLE a (id INTEGER);", migrations[0].Up)
+	s.Equal("DROP TABLE b;", migrations[1].Down)
+}
+
+func (s *SourceTests) TestNewFromSource_error() {
+	_, err := NewFromSource(MapSource{}, "migrations")
+	s.True(os.IsNotExist(err))
+	migrations, err := NewFromSource(MapSource{
+		"1_a.up.sql":   "CREATE TABLE a (id INTEGER);",
+		"1_a.down.sql": "DROP TABLE a;",
+		"2_b.up.sql":   "CREATE TABLE b (id INTEGER);",
+	}, ".")
+	s.Len(migrations, 1)
+	s.Contains(err.Error(), "could not find reverse migration for 2_b.up.sql")
+}
+
+func (s *SourceTests) TestNewFromSource_http() {
+	dir, err := ioutil.TempDir("", "migrations")
+	s.Nil(err)
+	defer os.RemoveAll(dir)
+	s.Nil(ioutil.WriteFile(path.Join(dir, "1_a.up.sql"), []byte("CREATE TABLE a (id INTEGER);"), 0644))
+	s.Nil(ioutil.WriteFile(path.Join(dir, "1_a.down.sql"), []byte("DROP TABLE a;"), 0644))
+	migrations, err := NewFromSource(HTTPSource{FileSystem: http.Dir(dir)}, "/")
+	s.Nil(err)
+	s.Len(migrations, 1)
+	s.Equal("DROP TABLE a;", migrations[0].Down)
+	migrations, err = NewFromDirectory(dir)
+	s.Nil(err)
+	s.Len(migrations, 1)
+	s.Equal("CREATE TABLE a (id INTEGER);", migrations[0].Up)
+}
+
+func (s *SourceTests) TestNewFromSource_recursive() {
+	migrations, err := NewFromSource(MapSource{
+		"migrations/users/10_users.up.sql":      "CREATE TABLE users (id INTEGER);",
+		"migrations/users/10_users.down.sql":    "DROP TABLE users;",
+		"migrations/billing/9_billing.up.sql":   "CREATE TABLE billing (id INTEGER);",
+		"migrations/billing/9_billing.down.sql": "DROP TABLE billing;",
+		"migrations/1_init.up.sql":              "SELECT 1;",
+		"migrations/1_init.down.sql":            "SELECT 1;",
+	}, "migrations")
+	s.Nil(err)
+	s.Len(migrations, 3)
+	s.Equal("1_init", migrations[0].Name)
+	s.Equal("9_billing", migrations[1].Name)
+	s.Equal("migrations/billing/9_billing", migrations[1].Path)
+	s.Equal("10_users", migrations[2].Name)
+	_, err = NewFromSource(MapSource{
+		"a/1_users.up.sql":   "",
+		"a/1_users.down.sql": "",
+		"b/1_orgs.up.sql":    "",
+		"b/1_orgs.down.sql":  "",
+	}, ".")
+	s.Contains(err.Error(), "duplicate migration version 1 used by '1_orgs' at 'b/1_orgs' and '1_users' at 'a/1_users'")
+}
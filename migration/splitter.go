@@ -0,0 +1,147 @@
+package migration
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/usvc/go-db"
+)
+
+var (
+	// batchSeparator matches the GO batch separator of Microsoft SQL Server
+	// scripts with an optional repeat count
+	batchSeparator = regexp.MustCompile(`(?i)^\s*GO(?:\s+(\d+))?\s*$`)
+	// delimiterDirective matches the DELIMITER command of MySQL clients
+	// which changes the statement delimiter
+	delimiterDirective = regexp.MustCompile(`(?i)^\s*DELIMITER\s+(\S+)\s*$`)
+)
+
+// DefaultDelimiter is the delimiter between statements of a script
+const DefaultDelimiter = ";"
+
+// SplitStatements splits the :script parameter into statements which can
+// be executed one by one by the :driver parameter. Delimiters inside string
+// literals, quoted identifiers and comments are ignored, as are
+// dollar-quoted bodies on PostgreSQL. MySQL scripts may change the
+// delimiter with DELIMITER lines (eg. for stored procedures) and Microsoft
+// SQL Server scripts are split into batches on GO lines only, a batch
+// followed by "GO <count>" is repeated <count> times. Statements which only
+// contain comments are dropped
+func SplitStatements(driver, script string) []string {
+	s := splitter{driver: driver, script: script, delimiter: DefaultDelimiter}
+	return s.split()
+}
+
+// splitter holds the state of SplitStatements
+type splitter struct {
+	driver     string
+	script     string
+	delimiter  string
+	start      int
+	hasContent bool
+	statements []string
+}
+
+func (s *splitter) split() []string {
+	tokens := db.Tokenize(s.driver, s.script)
+	end := 0
+	for i := 0; i < len(tokens); i++ {
+		start := end
+		end += len(tokens[i].Value)
+		switch tokens[i].Kind {
+		case db.TokenComment:
+			continue
+		case db.TokenString, db.TokenIdentifier:
+			s.hasContent = true
+			continue
+		}
+		for i+1 < len(tokens) && isCode(tokens[i+1].Kind) {
+			i++
+			end += len(tokens[i].Value)
+		}
+		s.scan(start, end)
+	}
+	s.add(len(s.script), 1)
+	return s.statements
+}
+
+// scan looks for delimiters and directives between the :start and :end
+// parameters, which must not contain string literals, quoted identifiers
+// or comments
+func (s *splitter) scan(start, end int) {
+	for i := start; i < end; {
+		if i == 0 || s.script[i-1] == '\n' {
+			if next, ok := s.directive(i, end); ok {
+				i = next
+				continue
+			}
+		}
+		c := s.script[i]
+		switch {
+		case s.driver != db.DriverMSSQL && strings.HasPrefix(s.script[i:end], s.delimiter):
+			s.add(i, 1)
+			i += len(s.delimiter)
+			s.start = i
+		default:
+			if c != ' ' && c != '\t' && c != '\r' && c != '\n' {
+				s.hasContent = true
+			}
+			i++
+		}
+	}
+}
+
+// add adds the statement ending at the :end parameter :count times if it
+// contains anything other than whitespace and comments
+func (s *splitter) add(end, count int) {
+	if s.hasContent {
+		statement := strings.TrimSpace(s.script[s.start:end])
+		for i := 0; i < count; i++ {
+			s.statements = append(s.statements, statement)
+		}
+	}
+	s.hasContent = false
+}
+
+// directive handles a DELIMITER or GO line starting at the :start
+// parameter, returning the index of the next line if there is one. Lines
+// which continue past the :end parameter are not directives
+func (s *splitter) directive(start, end int) (int, bool) {
+	lineEnd := strings.IndexByte(s.script[start:end], '\n')
+	next := start + lineEnd + 1
+	if lineEnd == -1 {
+		if end < len(s.script) {
+			return start, false
+		}
+		lineEnd = end - start
+		next = end
+	}
+	line := s.script[start : start+lineEnd]
+	switch s.driver {
+	case db.DriverMySQL:
+		if match := delimiterDirective.FindStringSubmatch(line); match != nil {
+			s.add(start, 1)
+			s.delimiter = match[1]
+			s.start = next
+			return next, true
+		}
+	case db.DriverMSSQL:
+		if match := batchSeparator.FindStringSubmatch(line); match != nil {
+			count := 1
+			if len(match[1]) > 0 {
+				count, _ = strconv.Atoi(match[1])
+			}
+			s.add(start, count)
+			s.start = next
+			return next, true
+		}
+	}
+	return start, false
+}
+
+// isCode returns true if tokens of the :kind parameter are SQL code rather
+// than string literals, quoted identifiers or comments
+func isCode(kind db.TokenKind) bool {
+	return kind == db.TokenText || kind == db.TokenPlaceholder || kind == db.TokenNamed || kind == db.TokenEscapedPlaceholder
+}
@@ -0,0 +1,66 @@
+package migration
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/usvc/go-db"
+)
+
+type SplitterTests struct {
+	suite.Suite
+}
+
+func TestSplitter(t *testing.T) {
+	suite.Run(t, &SplitterTests{})
+}
+
+func (s *SplitterTests) TestSplitStatements() {
+	s.Equal([]string{
+		"CREATE TABLE a (id INT)",
+		"INSERT INTO a VALUES ('a;b', \"c;d\")",
+	}, SplitStatements(db.DriverPostgreSQL, "CREATE TABLE a (id INT);\nINSERT INTO a VALUES ('a;b', \"c;d\");\n"))
+	s.Equal([]string{"SELECT 1 AS good"}, SplitStatements(db.DriverPostgreSQL, "SELECT 1 AS good"))
+	s.Nil(SplitStatements(db.DriverPostgreSQL, "\n-- nothing to see here;\n/* or here; */\n"))
+}
+
+func (s *SplitterTests) TestSplitStatements_comments() {
+	s.Equal([]string{
+		"-- first; statement\nSELECT 1",
+		"/* second; statement */ SELECT 2",
+	}, SplitStatements(db.DriverPostgreSQL, "-- first; statement\nSELECT 1;\n/* second; statement */ SELECT 2;\n-- trailing; comment"))
+	s.Equal([]string{"SELECT 1 /* a /* nested; */ comment; */", "SELECT 2"}, SplitStatements(db.DriverPostgreSQL, "SELECT 1 /* a /* nested; */ comment; */; SELECT 2"))
+	s.Equal([]string{"# a; comment\nSELECT 1", "SELECT 2"}, SplitStatements(db.DriverMySQL, "# a; comment\nSELECT 1; SELECT 2"))
+}
+
+func (s *SplitterTests) TestSplitStatements_mysql() {
+	s.Equal([]string{
+		"INSERT INTO a VALUES ('it\\'s; escaped', `b;c`)",
+		"SELECT 2",
+	}, SplitStatements(db.DriverMySQL, "INSERT INTO a VALUES ('it\\'s; escaped', `b;c`); SELECT 2;"))
+	s.Equal([]string{
+		"DROP PROCEDURE IF EXISTS p",
+		"CREATE PROCEDURE p()\nBEGIN\n  SELECT 1;\n  SELECT 2;\nEND",
+		"CALL p()",
+	}, SplitStatements(db.DriverMySQL, "DROP PROCEDURE IF EXISTS p;\nDELIMITER //\nCREATE PROCEDURE p()\nBEGIN\n  SELECT 1;\n  SELECT 2;\nEND //\ndelimiter ;\nCALL p();\n"))
+	s.Equal([]string{"SELECT \"a\\\";b\" FROM t WHERE c = ?", "SELECT 2"}, SplitStatements(db.DriverMySQL, "SELECT \"a\\\";b\" FROM t WHERE c = ?; SELECT 2"))
+}
+
+func (s *SplitterTests) TestSplitStatements_postgres() {
+	s.Equal([]string{
+		"CREATE FUNCTION f() RETURNS INT AS $$ BEGIN RETURN 1; END; $$ LANGUAGE plpgsql",
+		"CREATE FUNCTION g() RETURNS INT AS $body$ SELECT $1; $body$ LANGUAGE sql",
+		"SELECT 'it''s;'",
+	}, SplitStatements(db.DriverPostgreSQL, "CREATE FUNCTION f() RETURNS INT AS $$ BEGIN RETURN 1; END; $$ LANGUAGE plpgsql;\nCREATE FUNCTION g() RETURNS INT AS $body$ SELECT $1; $body$ LANGUAGE sql;\nSELECT 'it''s;';"))
+	s.Equal([]string{"SELECT a$b$ FROM t", "SELECT $b$;$b$"}, SplitStatements(db.DriverPostgreSQL, "SELECT a$b$ FROM t; SELECT $b$;$b$;"))
+	s.Equal([]string{"INSERT INTO t VALUES (E'a\\'; b')", "SELECT 2"}, SplitStatements(db.DriverPostgreSQL, "INSERT INTO t VALUES (E'a\\'; b');\nSELECT 2;"))
+}
+
+func (s *SplitterTests) TestSplitStatements_mssql() {
+	s.Equal([]string{
+		"CREATE TABLE [a;b] (id INT); INSERT INTO [a;b] VALUES (1)",
+		"SELECT 1",
+		"SELECT 1",
+		"/*\nGO\n*/ SELECT 2",
+	}, SplitStatements(db.DriverMSSQL, "CREATE TABLE [a;b] (id INT); INSERT INTO [a;b] VALUES (1)\nGO\nSELECT 1\nGO 2\n/*\nGO\n*/ SELECT 2\nGO\n"))
+}
@@ -0,0 +1,153 @@
+package migration
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+const (
+	// StatePending indicates a local migration which has not been recorded
+	StatePending = "pending"
+	// StateApplied indicates a migration which has been applied
+	StateApplied = "applied"
+	// StateBaselined indicates a migration which has been recorded as
+	// applied by a baseline without being executed
+	StateBaselined = "baselined"
+	// StateFailed indicates a migration which has been recorded as failed
+	StateFailed = "failed"
+	// StateInProgress indicates a migration which is being applied or rolled
+	// back (or whose process died while doing so)
+	StateInProgress = "in progress"
+	// StateStale indicates a migration which has been in progress for
+	// longer than Runner.StaleAfter, usually because the process applying
+	// or rolling it back died, see Runner.Repair
+	StateStale = "stale"
+	// StateRolledBack indicates a migration which has been rolled back and
+	// can be applied again
+	StateRolledBack = "rolled back"
+	// StateUnknown indicates a migration which has been recorded but does
+	// not exist locally
+	StateUnknown = "unknown"
+)
+
+// MigrationStatus describes the state of a migration by comparing the
+// local migration with its row in the migrations table
+type MigrationStatus struct {
+	// Name contains the name of the migration
+	Name string `json:"name" yaml:"name"`
+	// State contains one of the State* constants
+	State string `json:"state" yaml:"state"`
+	// Kind contains one of the Kind* constants
+	Kind string `json:"kind" yaml:"kind"`
+	// Status contains the status column of the migration, if recorded
+	Status string `json:"status,omitempty" yaml:"status,omitempty"`
+	// Error contains the error column of the migration, if recorded
+	Error *string `json:"error,omitempty" yaml:"error,omitempty"`
+	// AppliedAt contains the timestamp the migration was applied at
+	AppliedAt *time.Time `json:"applied_at,omitempty" yaml:"applied_at,omitempty"`
+	// StartedAt contains the timestamp the migration last started being
+	// applied or rolled back at
+	StartedAt *time.Time `json:"started_at,omitempty" yaml:"started_at,omitempty"`
+	// ChecksumMatch is true if the migration has been recorded, exists
+	// locally and its checksum matches the recorded one
+	ChecksumMatch bool `json:"checksum_match" yaml:"checksum_match"`
+	// Local is the local migration, nil if the State is StateUnknown
+	Local *Migration `json:"-" yaml:"-"`
+	// Remote is the recorded migration, nil if the State is StatePending
+	Remote *Migration `json:"-" yaml:"-"`
+}
+
+// Status returns the status of each of the :migrations parameter in the
+// order they should be applied followed by the recorded migrations which
+// do not exist locally in the order they were recorded. All migrations are
+// pending if the migrations table does not exist, a migrations table below
+// LatestSchemaVersion must be upgraded first (see CheckTable). Migrations
+// in progress for longer than DefaultStaleAfter are stale, see Runner.Status
+// for a configurable threshold
+func (e *Engine) Status(ctx context.Context, migrations Migrations) ([]MigrationStatus, error) {
+	return e.statuses(ctx, migrations, DefaultStaleAfter)
+}
+
+// Status returns the status of each of the Migrations of the runner and of
+// the recorded migrations which do not exist locally, see Engine.Status.
+// Migrations in progress for longer than StaleAfter are stale, matching
+// the migrations which Stale returns and Repair accepts
+func (r *Runner) Status(ctx context.Context) ([]MigrationStatus, error) {
+	return r.Engine.statuses(ctx, r.Migrations, r.staleAfter())
+}
+
+// statuses returns the statuses of Engine.Status, migrations in progress
+// for longer than the :staleAfter parameter are stale
+func (e *Engine) statuses(ctx context.Context, migrations Migrations, staleAfter time.Duration) ([]MigrationStatus, error) {
+	exists, err := e.CheckTable(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var history Migrations
+	if exists {
+		if history, err = e.List(ctx); err != nil {
+			return nil, err
+		}
+	}
+	remoteMigrations := map[string]*Migration{}
+	for i := 0; i < len(history); i++ {
+		remoteMigrations[history[i].Name] = history[i]
+	}
+	localMigrations := make(Migrations, len(migrations))
+	copy(localMigrations, migrations)
+	sort.Sort(localMigrations)
+	var statuses []MigrationStatus
+	for i := 0; i < len(localMigrations); i++ {
+		status := e.status(localMigrations[i], remoteMigrations[localMigrations[i].Name], staleAfter)
+		delete(remoteMigrations, localMigrations[i].Name)
+		statuses = append(statuses, status)
+	}
+	for i := 0; i < len(history); i++ {
+		if _, ok := remoteMigrations[history[i].Name]; ok {
+			statuses = append(statuses, e.status(nil, history[i], staleAfter))
+		}
+	}
+	return statuses, nil
+}
+
+// status returns the status of a migration given its :local and :remote
+// versions, either of which may be nil, it is stale if it has been in
+// progress for longer than the :staleAfter parameter
+func (e *Engine) status(local, remote *Migration, staleAfter time.Duration) MigrationStatus {
+	status := MigrationStatus{Local: local, Remote: remote}
+	if local != nil {
+		status.Name = local.Name
+		status.Kind = local.kind()
+	}
+	if remote == nil {
+		status.State = StatePending
+		return status
+	}
+	status.Name = remote.Name
+	status.Kind = remote.kind()
+	status.Status = remote.Status
+	status.Error = remote.Error
+	status.AppliedAt = remote.AppliedAt
+	status.StartedAt = remote.StartedAt
+	switch {
+	case remote.Status == StatusRolledBack:
+		status.State = StateRolledBack
+	case local == nil:
+		status.State = StateUnknown
+	case remote.Error != nil && len(*remote.Error) > 0:
+		status.State = StateFailed
+	case remote.Status == StatusApplied:
+		status.State = StateApplied
+	case remote.Status == StatusBaselined:
+		status.State = StateBaselined
+	case isStale(remote, staleAfter):
+		status.State = StateStale
+	default:
+		status.State = StateInProgress
+	}
+	if local != nil {
+		status.ChecksumMatch = e.recordedChecksum(remote) == Checksum(e.Dialect.Driver(), local)
+	}
+	return status
+}
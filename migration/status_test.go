@@ -0,0 +1,118 @@
+package migration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/suite"
+	"github.com/usvc/go-db"
+)
+
+type StatusTests struct {
+	suite.Suite
+}
+
+func TestStatus(t *testing.T) {
+	suite.Run(t, &StatusTests{})
+}
+
+func (s *StatusTests) TestStatus() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	appliedAt := time.Now()
+	columns := []string{"id", "name", "up", "down", "checksum", "error", "status", "applied_at", "created_at", "rolled_back_at", "kind", "started_at"}
+	expectCheckTable(mock, true, LatestSchemaVersion)
+	mock.ExpectQuery("SELECT (.+) FROM migrations ORDER BY id").WillReturnRows(sqlmock.NewRows(columns).
+		AddRow(1, "1_a", "UP a", "DOWN a", Checksum(db.DriverPostgreSQL, New("1_a", "UP a", "DOWN a")), nil, StatusApplied, appliedAt, appliedAt, nil, KindSQL, nil).
+		AddRow(2, "0_removed", "UP", "DOWN", nil, nil, StatusApplied, appliedAt, appliedAt, nil, KindSQL, nil).
+		AddRow(3, "2_b", "UP b", "DOWN b", nil, nil, StatusApplied, appliedAt, appliedAt, nil, KindSQL, nil).
+		AddRow(4, "3_c", "UP c", "DOWN c", nil, "this is expected", StatusApplying, nil, appliedAt, nil, KindSQL, nil).
+		AddRow(5, "4_d", "UP d", "DOWN d", nil, nil, StatusRollingBack, appliedAt, appliedAt, nil, KindSQL, appliedAt).
+		AddRow(6, "6_f", "UP f", "DOWN f", nil, nil, StatusApplying, nil, appliedAt, nil, KindSQL, appliedAt.Add(-2*DefaultStaleAfter)))
+	migrations := Migrations{
+		New("6_f", "UP f", "DOWN f"),
+		New("5_e", "UP e", "DOWN e"),
+		New("4_d", "UP d", "DOWN d"),
+		New("3_c", "UP c", "DOWN c"),
+		New("2_b", "UP b changed", "DOWN b"),
+		New("1_a", "UP a", "DOWN a"),
+	}
+	statuses, err := NewEngine(testDialect{}, "migrations", connection).Status(context.Background(), migrations)
+	s.Nil(err)
+	s.Len(statuses, 7)
+	expected := []struct {
+		name          string
+		state         string
+		checksumMatch bool
+	}{
+		{"1_a", StateApplied, true},
+		{"2_b", StateApplied, false},
+		{"3_c", StateFailed, true},
+		{"4_d", StateInProgress, true},
+		{"5_e", StatePending, false},
+		{"6_f", StateStale, true},
+		{"0_removed", StateUnknown, false},
+	}
+	for i := 0; i < len(expected); i++ {
+		s.Equal(expected[i].name, statuses[i].Name)
+		s.Equal(expected[i].state, statuses[i].State, expected[i].name)
+		s.Equal(expected[i].checksumMatch, statuses[i].ChecksumMatch, expected[i].name)
+	}
+	s.Equal(appliedAt, *statuses[0].AppliedAt)
+	s.Equal("this is expected", *statuses[2].Error)
+	s.Nil(statuses[4].Remote)
+	s.Nil(statuses[6].Local)
+	s.Equal(StatusApplying, statuses[5].Status)
+	s.NotNil(statuses[5].StartedAt)
+	s.Equal(KindSQL, statuses[0].Kind)
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *StatusTests) TestStatus_noTable() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	expectCheckTable(mock, false, 0)
+	statuses, err := NewEngine(testDialect{}, "migrations", connection).Status(context.Background(), Migrations{New("2_b", "UP b", "DOWN b"), New("1_a", "UP a", "DOWN a")})
+	s.Nil(err)
+	s.Len(statuses, 2)
+	s.Equal("1_a", statuses[0].Name)
+	s.Equal(StatePending, statuses[0].State)
+	s.Equal(StatePending, statuses[1].State)
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *StatusTests) TestStatus_outdatedTable() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	expectCheckTable(mock, true, LatestSchemaVersion-1)
+	_, err = NewEngine(testDialect{}, "migrations", connection).Status(context.Background(), Migrations{New("1_a", "UP a", "DOWN a")})
+	s.Contains(err.Error(), "call EnsureTable to upgrade it")
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *StatusTests) TestRunnerStatus_staleAfter() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	startedAt := time.Now().Add(-time.Minute)
+	columns := []string{"id", "name", "up", "down", "checksum", "error", "status", "applied_at", "created_at", "rolled_back_at", "kind", "started_at"}
+	for i := 0; i < 2; i++ {
+		expectCheckTable(mock, true, LatestSchemaVersion)
+		mock.ExpectQuery("SELECT (.+) FROM migrations ORDER BY id").WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(1, "1_a", "UP a", "DOWN a", nil, nil, StatusApplying, nil, startedAt, nil, KindSQL, startedAt))
+	}
+	runner := NewRunner(NewEngine(testDialect{}, "migrations", connection), Migrations{New("1_a", "UP a", "DOWN a")})
+	statuses, err := runner.Status(context.Background())
+	s.Nil(err)
+	s.Equal(StateInProgress, statuses[0].State)
+	runner.StaleAfter = time.Second
+	statuses, err = runner.Status(context.Background())
+	s.Nil(err)
+	s.Equal(StateStale, statuses[0].State)
+	s.Nil(mock.ExpectationsWereMet())
+}
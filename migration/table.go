@@ -0,0 +1,208 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+)
+
+// LatestSchemaVersion is the version of the schema of the migrations table
+// created and maintained by EnsureTable, it is the number of tableUpgrades
+const LatestSchemaVersion = 5
+
+// metaSchemaVersion is the name of the row of the metadata table which
+// holds the schema version of the migrations table
+const metaSchemaVersion = "schema_version"
+
+// tableUpgrade is a change to the schema of the migrations table which is
+// applied by EnsureTable to tables with a lower schema version. Upgrades
+// inspect the :columns of the table (lowercased) so that they can be
+// applied to tables which already have them and must update the :columns
+// when they add a column
+type tableUpgrade struct {
+	description string
+	upgrade     func(ctx context.Context, e *Engine, columns map[string]bool) error
+}
+
+// tableUpgrades are the upgrades of the migrations table in the order they
+// were introduced, the schema version of a table is the number of upgrades
+// it has received. Upgrades must only ever be appended, together with an
+// increment of LatestSchemaVersion and the same change to the DDL returned
+// by Dialect.CreateTableQuery
+var tableUpgrades = []tableUpgrade{
+	{"add checksum column", addColumn("checksum", func(Dialect) string { return "VARCHAR(64)" })},
+	{"compute checksums of migrations recorded before checksums", backfillChecksums},
+	{"add rolled_back_at column", addColumn("rolled_back_at", func(dialect Dialect) string { return dialect.TimestampType() })},
+	{"add kind column", addColumn("kind", func(Dialect) string { return "VARCHAR(16) NOT NULL DEFAULT '" + KindSQL + "'" })},
+	{"add started_at column", addColumn("started_at", func(dialect Dialect) string { return dialect.TimestampType() })},
+}
+
+// MetaTableName returns the name of the table which holds the metadata of
+// the migrations table, such as its schema version
+func (e *Engine) MetaTableName() string {
+	return e.TableName + "_meta"
+}
+
+// EnsureTable creates the migrations table, its log table and its metadata
+// table if they do not exist and applies the upgrades of the migrations
+// table it has not received yet, recording its new schema version in the
+// metadata table. It can be called on every run, but should be called by a
+// single process at a time (see Lock)
+func (e *Engine) EnsureTable(ctx context.Context) error {
+	if _, err := e.Connection.ExecContext(ctx, e.Dialect.CreateTableQuery(e.TableName)); err != nil {
+		return fmt.Errorf("failed to create migrations table '%s': '%s'", e.TableName, err)
+	}
+	if _, err := e.Connection.ExecContext(ctx, e.Dialect.CreateLogTableQuery(e.LogTableName())); err != nil {
+		return fmt.Errorf("failed to create log table '%s': '%s'", e.LogTableName(), err)
+	}
+	if _, err := e.Connection.ExecContext(ctx, e.Dialect.CreateMetaTableQuery(e.MetaTableName())); err != nil {
+		return fmt.Errorf("failed to create metadata table '%s': '%s'", e.MetaTableName(), err)
+	}
+	version, err := e.schemaVersion(ctx)
+	if err != nil {
+		return err
+	} else if version > LatestSchemaVersion {
+		return fmt.Errorf("failed to upgrade migrations table '%s': its schema version %v is newer than the latest known version %v", e.TableName, version, LatestSchemaVersion)
+	} else if version == LatestSchemaVersion {
+		return nil
+	}
+	columnList, err := e.columns(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve columns of migrations table '%s': '%s'", e.TableName, err)
+	}
+	columns := map[string]bool{}
+	for i := 0; i < len(columnList); i++ {
+		columns[columnList[i]] = true
+	}
+	for i := version; i < len(tableUpgrades); i++ {
+		if err := tableUpgrades[i].upgrade(ctx, e, columns); err != nil {
+			return fmt.Errorf("failed to upgrade migrations table '%s' to version %v (%s): '%s'", e.TableName, i+1, tableUpgrades[i].description, err)
+		}
+	}
+	return e.setSchemaVersion(ctx, LatestSchemaVersion)
+}
+
+// CheckTable returns false if the migrations table does not exist and an
+// error if it exists at a schema version other than LatestSchemaVersion,
+// in which case it has to be upgraded with EnsureTable before it is used
+func (e *Engine) CheckTable(ctx context.Context) (bool, error) {
+	exists, err := e.TableExists(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to check if migrations table '%s' exists: '%s'", e.TableName, err)
+	} else if !exists {
+		return false, nil
+	}
+	version, err := e.SchemaVersion(ctx)
+	if err != nil {
+		return true, err
+	} else if version != LatestSchemaVersion {
+		return true, fmt.Errorf("migrations table '%s' has schema version %v instead of %v, call EnsureTable to upgrade it", e.TableName, version, LatestSchemaVersion)
+	}
+	return true, nil
+}
+
+// RequireTable returns an error if the migrations table does not exist or
+// exists at a schema version other than LatestSchemaVersion, it guards
+// operations on single migrations which expect an up-to-date table
+func (e *Engine) RequireTable(ctx context.Context) error {
+	exists, err := e.CheckTable(ctx)
+	if err != nil {
+		return err
+	} else if !exists {
+		return fmt.Errorf("migrations table '%s' does not exist, call EnsureTable to create it", e.TableName)
+	}
+	return nil
+}
+
+// SchemaVersion returns the schema version of the migrations table recorded
+// in the metadata table, which is 0 for tables created before versions
+// were recorded
+func (e *Engine) SchemaVersion(ctx context.Context) (int, error) {
+	exists, err := e.tableExists(ctx, e.MetaTableName())
+	if err != nil {
+		return 0, fmt.Errorf("failed to check if metadata table '%s' exists: '%s'", e.MetaTableName(), err)
+	} else if !exists {
+		return 0, nil
+	}
+	return e.schemaVersion(ctx)
+}
+
+// schemaVersion returns the schema version recorded in the metadata table,
+// which must exist
+func (e *Engine) schemaVersion(ctx context.Context) (int, error) {
+	var value string
+	err := e.Connection.QueryRowContext(ctx, e.query("SELECT value FROM %s_meta WHERE name = ?"), metaSchemaVersion).Scan(&value)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	} else if err != nil {
+		return 0, fmt.Errorf("failed to retrieve schema version from metadata table '%s': '%s'", e.MetaTableName(), err)
+	}
+	version, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse schema version '%s' from metadata table '%s': '%s'", value, e.MetaTableName(), err)
+	}
+	return version, nil
+}
+
+// setSchemaVersion records the :version parameter as the schema version in
+// the metadata table, it must differ from the recorded version as some
+// drivers do not count unchanged rows as affected
+func (e *Engine) setSchemaVersion(ctx context.Context, version int) error {
+	result, err := e.Connection.ExecContext(ctx, e.query("UPDATE %s_meta SET value = ? WHERE name = ?"), strconv.Itoa(version), metaSchemaVersion)
+	if err != nil {
+		return fmt.Errorf("failed to record schema version in metadata table '%s': '%s'", e.MetaTableName(), err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected > 0 {
+		return nil
+	}
+	_, err = e.Connection.ExecContext(ctx, e.query("INSERT INTO %s_meta (name, value) VALUES (?, ?)"), metaSchemaVersion, strconv.Itoa(version))
+	if err != nil {
+		return fmt.Errorf("failed to record schema version in metadata table '%s': '%s'", e.MetaTableName(), err)
+	}
+	return nil
+}
+
+// addColumn returns an upgrade which adds the column named :name with the
+// definition returned by the :definition parameter if it does not exist
+func addColumn(name string, definition func(Dialect) string) func(context.Context, *Engine, map[string]bool) error {
+	return func(ctx context.Context, e *Engine, columns map[string]bool) error {
+		if columns[name] {
+			return nil
+		}
+		if _, err := e.Connection.ExecContext(ctx, e.Dialect.AddColumnQuery(e.TableName, name, definition(e.Dialect))); err != nil {
+			return err
+		}
+		columns[name] = true
+		return nil
+	}
+}
+
+// backfillChecksums computes the checksums of the rows recorded before
+// checksums were introduced from their scripts
+func backfillChecksums(ctx context.Context, e *Engine, columns map[string]bool) error {
+	rows, err := e.Connection.QueryContext(ctx, e.query("SELECT id, up, down FROM %s WHERE checksum IS NULL OR checksum = ''"))
+	if err != nil {
+		return err
+	}
+	var migrations Migrations
+	for rows.Next() {
+		migration := &Migration{}
+		if err := rows.Scan(&migration.ID, &migration.Up, &migration.Down); err != nil {
+			rows.Close()
+			return err
+		}
+		migrations = append(migrations, migration)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for i := 0; i < len(migrations); i++ {
+		_, err := e.Connection.ExecContext(ctx, e.query("UPDATE %s SET checksum = ? WHERE id = ?"), Checksum(e.Dialect.Driver(), migrations[i]), migrations[i].ID)
+		if err != nil {
+			return fmt.Errorf("failed to set checksum of migration with id %v: '%s'", migrations[i].ID, err)
+		}
+	}
+	return nil
+}
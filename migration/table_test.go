@@ -0,0 +1,143 @@
+package migration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/suite"
+	"github.com/usvc/go-db"
+)
+
+type TableTests struct {
+	suite.Suite
+}
+
+func TestTable(t *testing.T) {
+	suite.Run(t, &TableTests{})
+}
+
+func (s *TableTests) TestLatestSchemaVersion() {
+	s.Equal(len(tableUpgrades), LatestSchemaVersion)
+}
+
+func (s *TableTests) TestEnsureTable_upgrade() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	mock.ExpectExec("CREATE TABLE migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE migrations_log").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE migrations_meta").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT value FROM migrations_meta WHERE name = \\$1").WithArgs("schema_version").
+		WillReturnRows(sqlmock.NewRows([]string{"value"}))
+	mock.ExpectQuery("SELECT name FROM columns WHERE table_name = \\$1").WithArgs("migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("ID").AddRow("name").AddRow("Rolled_Back_At"))
+	mock.ExpectExec("ALTER TABLE migrations ADD COLUMN checksum VARCHAR\\(64\\)").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT id, up, down FROM migrations WHERE checksum IS NULL OR checksum = ''").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "up", "down"}).AddRow(1, "UP a", "DOWN a"))
+	mock.ExpectExec("UPDATE migrations SET checksum = \\$1 WHERE id = \\$2").
+		WithArgs(Checksum(db.DriverPostgreSQL, New("a", "UP a", "DOWN a")), 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("ALTER TABLE migrations ADD COLUMN kind VARCHAR\\(16\\) NOT NULL DEFAULT 'sql'").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("ALTER TABLE migrations ADD COLUMN started_at TIMESTAMPTZ").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE migrations_meta SET value = \\$1 WHERE name = \\$2").WithArgs("5", "schema_version").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO migrations_meta \\(name, value\\) VALUES \\(\\$1, \\$2\\)").WithArgs("schema_version", "5").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	s.Nil(NewEngine(testDialect{}, "migrations", connection).EnsureTable(context.Background()))
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *TableTests) TestEnsureTable_partial() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	mock.ExpectExec("CREATE TABLE migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE migrations_log").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE migrations_meta").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT value FROM migrations_meta").WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow("3"))
+	mock.ExpectQuery("SELECT name FROM columns").WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("checksum"))
+	mock.ExpectExec("ALTER TABLE migrations ADD COLUMN kind").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("ALTER TABLE migrations ADD COLUMN started_at").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE migrations_meta SET value = \\$1").WithArgs("5", "schema_version").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	s.Nil(NewEngine(testDialect{}, "migrations", connection).EnsureTable(context.Background()))
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *TableTests) TestEnsureTable_current() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	expectEnsureTable(mock)
+	s.Nil(NewEngine(testDialect{}, "migrations", connection).EnsureTable(context.Background()))
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *TableTests) TestEnsureTable_error_newer() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	mock.ExpectExec("CREATE TABLE migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE migrations_log").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE migrations_meta").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT value FROM migrations_meta").WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow("99"))
+	err = NewEngine(testDialect{}, "migrations", connection).EnsureTable(context.Background())
+	s.Contains(err.Error(), "its schema version 99 is newer than the latest known version")
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *TableTests) TestSchemaVersion() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM tables").WithArgs("migrations_meta").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM tables").WithArgs("migrations_meta").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery("SELECT value FROM migrations_meta").WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow("2"))
+	engine := NewEngine(testDialect{}, "migrations", connection)
+	version, err := engine.SchemaVersion(context.Background())
+	s.Nil(err)
+	s.Equal(0, version)
+	version, err = engine.SchemaVersion(context.Background())
+	s.Nil(err)
+	s.Equal(2, version)
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *TableTests) TestCheckTable() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	expectCheckTable(mock, false, 0)
+	expectCheckTable(mock, true, LatestSchemaVersion)
+	expectCheckTable(mock, true, 0)
+	engine := NewEngine(testDialect{}, "migrations", connection)
+	exists, err := engine.CheckTable(context.Background())
+	s.Nil(err)
+	s.False(exists)
+	exists, err = engine.CheckTable(context.Background())
+	s.Nil(err)
+	s.True(exists)
+	exists, err = engine.CheckTable(context.Background())
+	s.True(exists)
+	s.Contains(err.Error(), "has schema version 0 instead of 5, call EnsureTable to upgrade it")
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *TableTests) TestRequireTable() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	expectCheckTable(mock, false, 0)
+	expectCheckTable(mock, true, LatestSchemaVersion)
+	expectCheckTable(mock, true, 0)
+	engine := NewEngine(testDialect{}, "migrations", connection)
+	err = engine.RequireTable(context.Background())
+	s.Contains(err.Error(), "migrations table 'migrations' does not exist, call EnsureTable to create it")
+	s.Nil(engine.RequireTable(context.Background()))
+	err = engine.RequireTable(context.Background())
+	s.Contains(err.Error(), "call EnsureTable to upgrade it")
+	s.Nil(mock.ExpectationsWereMet())
+}
@@ -0,0 +1,82 @@
+package migration
+
+import (
+	"fmt"
+	"strings"
+)
+
+func stringEndsWith(test, postfix string) bool {
+	startIndexOfPostfix := strings.Index(test, postfix)
+	if startIndexOfPostfix != -1 && startIndexOfPostfix == len(test)-len(postfix) {
+		return true
+	}
+	return false
+}
+
+func stringSliceContains(test []string, item string) bool {
+	for i := 0; i < len(test); i++ {
+		if test[i] == item {
+			return true
+		}
+	}
+	return false
+}
+
+func GetMigrationNamesFromFilenames(filenameList []string) ([]string, []error) {
+	var acceptedFilenames []string
+	var rejectedFilenames []error
+	for i := 0; i < len(filenameList); i++ {
+		var filenameWithoutExtension string
+		filename := filenameList[i]
+		accepted := true
+		switch true {
+		case stringEndsWith(filename, ".up.sql"):
+			filenameWithoutExtension = filename[:len(filename)-len(".up.sql")]
+		case stringEndsWith(filename, ".down.sql"):
+			filenameWithoutExtension = filename[:len(filename)-len(".down.sql")]
+		default:
+			rejectedFilenames = append(rejectedFilenames, fmt.Errorf("filename %s does not end with .{up, down}.sql", filename))
+			accepted = false
+		}
+		acceptedBefore := stringSliceContains(acceptedFilenames, filenameWithoutExtension)
+		if accepted && !acceptedBefore {
+			migrationPairIsFound := stringSliceContains(filenameList, filenameWithoutExtension+".up.sql") &&
+				stringSliceContains(filenameList, filenameWithoutExtension+".down.sql")
+			if migrationPairIsFound {
+				acceptedFilenames = append(acceptedFilenames, filenameWithoutExtension)
+			} else {
+				rejectedFilenames = append(rejectedFilenames, fmt.Errorf("could not find reverse migration for %s", filename))
+			}
+		}
+	}
+	if len(rejectedFilenames) > 0 {
+		return acceptedFilenames, rejectedFilenames
+	}
+	return acceptedFilenames, nil
+}
+
+func NewFromFile(name, upFilePath, downFilePath string) (*Migration, error) {
+	return newFromSourceFiles(OSSource{}, name, upFilePath, downFilePath)
+}
+
+func NewFromDirectory(directoryPath string) (Migrations, error) {
+	return NewFromSource(OSSource{}, directoryPath)
+}
+
+func NormalizeQuery(query string) string {
+	return strings.Trim(
+		strings.ReplaceAll(
+			strings.ReplaceAll(
+				query,
+				"\t", " ",
+			),
+			"\n", " ",
+		), "\t\n\r ",
+	)
+}
+
+// Placeholders returns :count comma-separated `?` placeholders for use in
+// queries passed to the Engine or built by a Dialect
+func Placeholders(count int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", count), ", ")
+}
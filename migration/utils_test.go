@@ -0,0 +1,50 @@
+package migration
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type UtilsTests struct {
+	suite.Suite
+}
+
+func TestUtils(t *testing.T) {
+	suite.Run(t, &UtilsTests{})
+}
+
+func (s *UtilsTests) TestGetMigrationNamesFromFilenames() {
+	migrationNames, err := GetMigrationNamesFromFilenames([]string{
+		"a.up.sql",
+		"a.down.sql",
+		"B.down.sql",
+		"B.up.sql",
+	})
+	s.Nil(err)
+	s.EqualValues([]string{"a", "B"}, migrationNames)
+}
+
+func (s *UtilsTests) TestNormalizeQuery() {
+	s.Equal("CREATE TABLE a (id INTEGER);", NormalizeQuery("\tCREATE TABLE a\t(id INTEGER);\n"))
+}
+
+func (s *UtilsTests) TestPlaceholders() {
+	s.Equal("", Placeholders(0))
+	s.Equal("?", Placeholders(1))
+	s.Equal("?, ?, ?", Placeholders(3))
+}
+
+func (s *UtilsTests) Test_stringEndsWith() {
+	s.True(stringEndsWith("a.up.sql", ".up.sql"))
+	s.False(stringEndsWith("a.sql", ".up.sql"))
+	s.False(stringEndsWith("a.up", ".up.sql"))
+	s.False(stringEndsWith("a", ".up.sql"))
+	s.False(stringEndsWith("Makefile", ".up.sql"))
+}
+
+func (s *UtilsTests) Test_stringSliceContains() {
+	s.True(stringSliceContains([]string{"a", "b"}, "b"))
+	s.True(stringSliceContains([]string{"a", "b"}, "a"))
+	s.False(stringSliceContains([]string{"a", "b"}, "c"))
+}
@@ -0,0 +1,130 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Named rewrites :name parameters in the :query parameter to positional
+// placeholders for the driver named :driverName, returning the rewritten
+// query and the arguments in placeholder order. The :arg parameter should
+// be a map[string]interface{} or a struct (or pointer to one) whose fields
+// are mapped using `db:"name"` tags. Slice values (other than []byte and
+// types implementing driver.Valuer such as pq.StringArray) are expanded
+// into a list of placeholders for use in IN (:name) clauses. Question
+// marks which are not placeholders must be escaped as ??, see Rebind
+func Named(driverName, query string, arg interface{}) (string, []interface{}, error) {
+	lookup, err := getNamedLookup(arg)
+	if err != nil {
+		return "", nil, err
+	}
+	var rebound strings.Builder
+	var args []interface{}
+	tokens := Tokenize(driverName, query)
+	for i := 0; i < len(tokens); i++ {
+		switch tokens[i].Kind {
+		case TokenPlaceholder:
+			return "", nil, fmt.Errorf("positional placeholders cannot be mixed with named parameters")
+		case TokenEscapedPlaceholder:
+			rebound.WriteString("?")
+		case TokenNamed:
+			name := tokens[i].Value[1:]
+			value, ok := lookup(name)
+			if !ok {
+				return "", nil, fmt.Errorf("no value was provided for parameter '%s'", name)
+			}
+			expanded := reflect.ValueOf(value)
+			if !isExpandable(value) {
+				args = append(args, value)
+				rebound.WriteString(placeholder(driverName, len(args)))
+				continue
+			}
+			if expanded.Len() == 0 {
+				return "", nil, fmt.Errorf("an empty slice was provided for parameter '%s'", name)
+			}
+			for j := 0; j < expanded.Len(); j++ {
+				if j > 0 {
+					rebound.WriteString(", ")
+				}
+				args = append(args, expanded.Index(j).Interface())
+				rebound.WriteString(placeholder(driverName, len(args)))
+			}
+		default:
+			rebound.WriteString(tokens[i].Value)
+		}
+	}
+	return rebound.String(), args, nil
+}
+
+// isExpandable returns true if the :value parameter is a slice which should
+// be expanded into a list of placeholders, byte slices and slices which
+// implement driver.Valuer are passed to the driver as a single value
+func isExpandable(value interface{}) bool {
+	if _, ok := value.(driver.Valuer); ok {
+		return false
+	}
+	valueType := reflect.TypeOf(value)
+	return valueType != nil && valueType.Kind() == reflect.Slice && valueType.Elem().Kind() != reflect.Uint8
+}
+
+// getNamedLookup returns a function that retrieves the value for a named
+// parameter from the :arg parameter
+func getNamedLookup(arg interface{}) (func(string) (interface{}, bool), error) {
+	if values, ok := arg.(map[string]interface{}); ok {
+		return func(name string) (interface{}, bool) {
+			value, ok := values[name]
+			return value, ok
+		}, nil
+	}
+	structValue := reflect.ValueOf(arg)
+	if structValue.Kind() == reflect.Ptr && !structValue.IsNil() {
+		structValue = structValue.Elem()
+	}
+	if structValue.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("expected a map[string]interface{} or a struct but received '%T'", arg)
+	}
+	fieldMap := getFieldMap(structValue.Type())
+	return func(name string) (interface{}, bool) {
+		index, ok := fieldMap[strings.ToLower(name)]
+		if !ok {
+			return nil, false
+		}
+		return structValue.FieldByIndex(index).Interface(), true
+	}, nil
+}
+
+// NamedExec executes the :query parameter after expanding its named
+// parameters using the :arg parameter
+func (c *Connection) NamedExec(query string, arg interface{}) (sql.Result, error) {
+	return c.NamedExecContext(context.Background(), query, arg)
+}
+
+// NamedExecContext executes the :query parameter after expanding its named
+// parameters using the :arg parameter
+func (c *Connection) NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
+	namedQuery, args, err := Named(c.Driver, query, arg)
+	if err != nil {
+		return nil, err
+	}
+	return c.DB.ExecContext(ctx, namedQuery, args...)
+}
+
+// NamedQuery runs the :query parameter after expanding its named
+// parameters using the :arg parameter
+func (c *Connection) NamedQuery(query string, arg interface{}) (*sql.Rows, error) {
+	return c.NamedQueryContext(context.Background(), query, arg)
+}
+
+// NamedQueryContext runs the :query parameter after expanding its named
+// parameters using the :arg parameter
+func (c *Connection) NamedQueryContext(ctx context.Context, query string, arg interface{}) (*sql.Rows, error) {
+	namedQuery, args, err := Named(c.Driver, query, arg)
+	if err != nil {
+		return nil, err
+	}
+	return c.DB.QueryContext(ctx, namedQuery, args...)
+}
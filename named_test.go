@@ -0,0 +1,93 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/suite"
+)
+
+type NamedTests struct {
+	suite.Suite
+}
+
+func TestNamed(t *testing.T) {
+	suite.Run(t, &NamedTests{})
+}
+
+func (s *NamedTests) TestNamed_map() {
+	query, args, err := Named(DriverPostgreSQL, "SELECT * FROM users WHERE id = :id AND org = :org AND note = ':id' AND created::date = :date", map[string]interface{}{
+		"id":   1,
+		"org":  "usvc",
+		"date": "2020-01-01",
+	})
+	s.Nil(err)
+	s.Equal("SELECT * FROM users WHERE id = $1 AND org = $2 AND note = ':id' AND created::date = $3", query)
+	s.Equal([]interface{}{1, "usvc", "2020-01-01"}, args)
+}
+
+func (s *NamedTests) TestNamed_struct() {
+	arg := struct {
+		IDs  []int64 `db:"ids"`
+		Data []byte  `db:"data"`
+		Org  string
+	}{
+		IDs:  []int64{1, 2, 3},
+		Data: []byte("data"),
+		Org:  "usvc",
+	}
+	query, args, err := Named(DriverMSSQL, "SELECT * FROM users WHERE id IN (:ids) AND data = :data AND org = :org", &arg)
+	s.Nil(err)
+	s.Equal("SELECT * FROM users WHERE id IN (@p1, @p2, @p3) AND data = @p4 AND org = @p5", query)
+	s.Equal([]interface{}{int64(1), int64(2), int64(3), []byte("data"), "usvc"}, args)
+	query, _, err = Named(DriverMySQL, "SELECT * FROM users WHERE id IN (:ids)", arg)
+	s.Nil(err)
+	s.Equal("SELECT * FROM users WHERE id IN (?, ?, ?)", query)
+}
+
+func (s *NamedTests) TestNamed_valuer() {
+	tags := pq.StringArray{"a", "b"}
+	query, args, err := Named(DriverPostgreSQL, "SELECT * FROM posts WHERE tags && :tags", map[string]interface{}{"tags": tags})
+	s.Nil(err)
+	s.Equal("SELECT * FROM posts WHERE tags && $1", query)
+	s.Equal([]interface{}{tags}, args)
+}
+
+func (s *NamedTests) TestNamed_escapedPlaceholder() {
+	query, args, err := Named(DriverPostgreSQL, "SELECT * FROM posts WHERE meta ?? 'draft' AND id = :id", map[string]interface{}{"id": 1})
+	s.Nil(err)
+	s.Equal("SELECT * FROM posts WHERE meta ? 'draft' AND id = $1", query)
+	s.Equal([]interface{}{1}, args)
+}
+
+func (s *NamedTests) TestNamed_comments() {
+	query, args, err := Named(DriverMySQL, "SELECT 1 # note :x\nFROM t WHERE id = :id", map[string]interface{}{"id": 1})
+	s.Nil(err)
+	s.Equal("SELECT 1 # note :x\nFROM t WHERE id = ?", query)
+	s.Equal([]interface{}{1}, args)
+	_, _, err = Named(DriverPostgreSQL, "SELECT 1 # :x", map[string]interface{}{})
+	s.Contains(err.Error(), "'x'")
+}
+
+func (s *NamedTests) TestNamed_error() {
+	_, _, err := Named(DriverMySQL, "SELECT * FROM users WHERE id = :id", map[string]interface{}{})
+	s.Contains(err.Error(), "'id'")
+	_, _, err = Named(DriverMySQL, "SELECT * FROM users WHERE id IN (:ids)", map[string]interface{}{"ids": []int{}})
+	s.Contains(err.Error(), "empty slice")
+	_, _, err = Named(DriverMySQL, "SELECT * FROM users WHERE id = ? AND org = :org", map[string]interface{}{"org": 1})
+	s.Contains(err.Error(), "cannot be mixed")
+	_, _, err = Named(DriverMySQL, "SELECT 1", 1)
+	s.Contains(err.Error(), "'int'")
+}
+
+func (s *NamedTests) TestConnection_namedExec() {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	s.Nil(err)
+	defer db.Close()
+	mock.ExpectExec("DELETE FROM users WHERE id IN ($1, $2)").WithArgs(1, 2).WillReturnResult(sqlmock.NewResult(0, 2))
+	connection := Wrap(db, DriverPostgreSQL)
+	_, err = connection.NamedExec("DELETE FROM users WHERE id IN (:ids)", map[string]interface{}{"ids": []int{1, 2}})
+	s.Nil(err)
+	s.Nil(mock.ExpectationsWereMet())
+}
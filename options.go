@@ -45,6 +45,10 @@ type Options struct {
 	Driver string
 	// Params define connection parameters to use in the data source name (DSN)
 	Params map[string]string
+	// SessionInit defines statements to execute on every new physical connection
+	// before it is used (eg. "SET time_zone = '+00:00'"), a failing statement
+	// causes the connection attempt to fail
+	SessionInit []string
 }
 
 // AssignDefaults takes in a pointer to a connection :options parameter
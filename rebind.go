@@ -0,0 +1,124 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+)
+
+// Connection wraps a *sql.DB and rewrites `?` placeholders in queries to
+// the placeholder style of its driver before they are sent to the database
+// so that the same SQL can be used with all SupportedDrivers. Transactions
+// started from a Connection are not rewritten, use Rebind for those
+type Connection struct {
+	*sql.DB
+	// Driver is the name of the driver used by the connection
+	Driver string
+}
+
+// Rebind rewrites `?` placeholders in the :query parameter to the
+// placeholder style of the driver named :driver ($1 for PostgreSQL and
+// @p1 for Microsoft SQL Server), ignoring question marks in string
+// literals, quoted identifiers and comments. Question marks which are not
+// placeholders, such as the ?, ?| and ?& jsonb operators of PostgreSQL,
+// must be escaped as ?? and are written as a single ? for all drivers
+func Rebind(driver, query string) string {
+	var rebound strings.Builder
+	tokens := Tokenize(driver, query)
+	position := 0
+	for i := 0; i < len(tokens); i++ {
+		switch tokens[i].Kind {
+		case TokenPlaceholder:
+			position++
+			rebound.WriteString(placeholder(driver, position))
+		case TokenEscapedPlaceholder:
+			rebound.WriteString("?")
+		default:
+			rebound.WriteString(tokens[i].Value)
+		}
+	}
+	return rebound.String()
+}
+
+// placeholder returns the placeholder for the 1-indexed :position for
+// the driver named :driver
+func placeholder(driver string, position int) string {
+	switch driver {
+	case DriverPostgreSQL:
+		return "$" + strconv.Itoa(position)
+	case DriverMSSQL:
+		return "@p" + strconv.Itoa(position)
+	}
+	return "?"
+}
+
+// Wrap returns a Connection which rewrites placeholders for the driver
+// named :driver on the :existingConnection parameter
+func Wrap(existingConnection *sql.DB, driver string) *Connection {
+	return &Connection{DB: existingConnection, Driver: driver}
+}
+
+// GetConnection returns the database connection as retrieved by Get wrapped
+// in a Connection for its driver, nil is returned if the connection does
+// not exist
+func GetConnection(optionalConnectionName ...string) *Connection {
+	connectionName := DefaultConnectionName
+	if len(optionalConnectionName) > 0 {
+		connectionName = optionalConnectionName[0]
+	}
+	if _, ok := connection[connectionName]; !ok {
+		connectionName = DefaultConnectionName
+	}
+	existingConnection, ok := connection[connectionName]
+	if !ok {
+		return nil
+	}
+	return Wrap(existingConnection, connectionDetails[connectionName].driver)
+}
+
+// Rebind rewrites placeholders in the :query parameter for the driver of
+// the connection
+func (c *Connection) Rebind(query string) string {
+	return Rebind(c.Driver, query)
+}
+
+// Exec executes the :query parameter after rewriting its placeholders
+func (c *Connection) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return c.DB.Exec(c.Rebind(query), args...)
+}
+
+// ExecContext executes the :query parameter after rewriting its placeholders
+func (c *Connection) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return c.DB.ExecContext(ctx, c.Rebind(query), args...)
+}
+
+// Prepare prepares the :query parameter after rewriting its placeholders
+func (c *Connection) Prepare(query string) (*sql.Stmt, error) {
+	return c.DB.Prepare(c.Rebind(query))
+}
+
+// PrepareContext prepares the :query parameter after rewriting its placeholders
+func (c *Connection) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return c.DB.PrepareContext(ctx, c.Rebind(query))
+}
+
+// Query runs the :query parameter after rewriting its placeholders
+func (c *Connection) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return c.DB.Query(c.Rebind(query), args...)
+}
+
+// QueryContext runs the :query parameter after rewriting its placeholders
+func (c *Connection) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return c.DB.QueryContext(ctx, c.Rebind(query), args...)
+}
+
+// QueryRow runs the :query parameter after rewriting its placeholders
+func (c *Connection) QueryRow(query string, args ...interface{}) *sql.Row {
+	return c.DB.QueryRow(c.Rebind(query), args...)
+}
+
+// QueryRowContext runs the :query parameter after rewriting its placeholders
+func (c *Connection) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return c.DB.QueryRowContext(ctx, c.Rebind(query), args...)
+}
@@ -0,0 +1,52 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/suite"
+)
+
+type RebindTests struct {
+	suite.Suite
+}
+
+func TestRebind(t *testing.T) {
+	suite.Run(t, &RebindTests{})
+}
+
+func (s *RebindTests) TestRebind() {
+	query := "SELECT '?', `?` FROM t WHERE a = ? AND b = ? -- ?"
+	s.Equal(query, Rebind(DriverMySQL, query))
+	s.Equal("SELECT '?', `?` FROM t WHERE a = $1 AND b = $2 -- ?", Rebind(DriverPostgreSQL, query))
+	s.Equal("SELECT '?', `?` FROM t WHERE a = @p1 AND b = @p2 -- ?", Rebind(DriverMSSQL, query))
+	s.Equal(`SELECT a FROM t WHERE b ? 'c' AND b ?| $1 AND d = $2`, Rebind(DriverPostgreSQL, `SELECT a FROM t WHERE b ?? 'c' AND b ??| ? AND d = ?`))
+	s.Equal("SELECT a FROM t WHERE b = ? AND c = '??'", Rebind(DriverMySQL, "SELECT a FROM t WHERE b = ? AND c = '??'"))
+	s.Equal(`SELECT E'it\'s ?' FROM t WHERE a = $1`, Rebind(DriverPostgreSQL, `SELECT E'it\'s ?' FROM t WHERE a = ?`))
+}
+
+func (s *RebindTests) TestConnection() {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	s.Nil(err)
+	defer db.Close()
+	mock.ExpectExec("UPDATE t SET a = $1 WHERE b = $2").WithArgs(1, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT a FROM t WHERE b = $1").WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"a"}).AddRow(1))
+	connection := Wrap(db, DriverPostgreSQL)
+	_, err = connection.Exec("UPDATE t SET a = ? WHERE b = ?", 1, 2)
+	s.Nil(err)
+	var a int
+	s.Nil(connection.QueryRow("SELECT a FROM t WHERE b = ?", 2).Scan(&a))
+	s.Equal(1, a)
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *RebindTests) TestGetConnection() {
+	db, _, err := sqlmock.New()
+	s.Nil(err)
+	defer db.Close()
+	s.Nil(Import(db, "__get_connection"))
+	connection := GetConnection("__get_connection")
+	s.NotNil(connection)
+	s.Equal(db, connection.DB)
+	s.Nil(GetConnection("__get_connection_does_not_exist_and_no_default"))
+}
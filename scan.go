@@ -0,0 +1,227 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+const (
+	// StructTag is the struct field tag used to map result set columns to
+	// struct fields, use `db:"-"` to exclude a field from being mapped
+	StructTag = "db"
+)
+
+var (
+	// DefaultStructScanner is the StructScanner used by QueryStructs,
+	// QueryOne and ScanStructs
+	DefaultStructScanner = StructScanner{}
+	// fieldMaps caches the column name to field index mapping of struct
+	// types so that reflection is only done once per type
+	fieldMaps sync.Map
+)
+
+// Queryer is implemented by *sql.DB, *sql.Conn and *sql.Tx
+type Queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// StructScanner maps result set columns onto fields of a struct by their
+// `db:"column"` tags, untagged fields are mapped using their lowercased
+// name. Fields which are pointers receive nil for NULL values
+type StructScanner struct {
+	// Strict causes an error to be returned when a result set contains a
+	// column which does not map to any field
+	Strict bool
+}
+
+// QueryStructs runs the :query parameter and scans all returned rows into
+// the :dest parameter, which should be a pointer to a slice of structs or
+// a pointer to a slice of pointers to structs, see ScanStructs
+func (s StructScanner) QueryStructs(ctx context.Context, connection Queryer, query string, dest interface{}, args ...interface{}) error {
+	if _, _, err := getSliceDestination(dest); err != nil {
+		return err
+	}
+	rows, err := connection.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	return s.ScanStructs(rows, dest)
+}
+
+// QueryOne runs the :query parameter and scans the first returned row into
+// the :dest parameter, which should be a pointer to a struct. sql.ErrNoRows
+// is returned if no rows were returned
+func (s StructScanner) QueryOne(ctx context.Context, connection Queryer, query string, dest interface{}, args ...interface{}) error {
+	destination := reflect.ValueOf(dest)
+	if destination.Kind() != reflect.Ptr || destination.IsNil() || destination.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("expected a non-nil pointer to a struct but received '%T'", dest)
+	}
+	rows, err := connection.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return sql.ErrNoRows
+	}
+	targets, err := s.getTargets(rows, destination.Elem().Type())
+	if err != nil {
+		return err
+	}
+	if err := rows.Scan(targets.pointers(destination.Elem())...); err != nil {
+		return err
+	}
+	return rows.Close()
+}
+
+// ScanStructs scans all remaining rows in the :rows parameter into the
+// :dest parameter, which should be a pointer to a slice of structs or a
+// pointer to a slice of pointers to structs. The slice is replaced by the
+// scanned rows rather than appended to, it is nil if there are none
+func (s StructScanner) ScanStructs(rows *sql.Rows, dest interface{}) error {
+	slice, structType, err := getSliceDestination(dest)
+	if err != nil {
+		return err
+	}
+	slice = reflect.Zero(slice.Type())
+	isPointer := slice.Type().Elem().Kind() == reflect.Ptr
+	targets, err := s.getTargets(rows, structType)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		element := reflect.New(structType)
+		if err := rows.Scan(targets.pointers(element.Elem())...); err != nil {
+			return err
+		}
+		if isPointer {
+			slice = reflect.Append(slice, element)
+		} else {
+			slice = reflect.Append(slice, element.Elem())
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	reflect.ValueOf(dest).Elem().Set(slice)
+	return nil
+}
+
+// getSliceDestination verifies that the :dest parameter is a pointer to a
+// slice of structs or pointers to structs, returning the slice and the
+// struct type
+func getSliceDestination(dest interface{}) (reflect.Value, reflect.Type, error) {
+	destination := reflect.ValueOf(dest)
+	if destination.Kind() != reflect.Ptr || destination.IsNil() || destination.Elem().Kind() != reflect.Slice {
+		return reflect.Value{}, nil, fmt.Errorf("expected a non-nil pointer to a slice but received '%T'", dest)
+	}
+	structType := destination.Elem().Type().Elem()
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+	if structType.Kind() != reflect.Struct {
+		return reflect.Value{}, nil, fmt.Errorf("expected a slice of structs but received '%T'", dest)
+	}
+	return destination.Elem(), structType, nil
+}
+
+// getTargets resolves the field index of every column in the :rows
+// parameter for the struct type :structType
+func (s StructScanner) getTargets(rows *sql.Rows, structType reflect.Type) (targets, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	fieldMap := getFieldMap(structType)
+	columnTargets := make(targets, len(columns))
+	for i := 0; i < len(columns); i++ {
+		if index, ok := fieldMap[strings.ToLower(columns[i])]; ok {
+			columnTargets[i] = index
+		} else if s.Strict {
+			return nil, fmt.Errorf("column '%s' does not map to any field of '%s'", columns[i], structType)
+		}
+	}
+	return columnTargets, nil
+}
+
+// targets holds the field index of each column in a result set, where
+// unmapped columns have a nil index
+type targets [][]int
+
+// pointers returns pointers to the fields of the :element parameter in
+// the order of the result set columns
+func (t targets) pointers(element reflect.Value) []interface{} {
+	pointers := make([]interface{}, len(t))
+	for i := 0; i < len(t); i++ {
+		if t[i] == nil {
+			pointers[i] = new(interface{})
+			continue
+		}
+		pointers[i] = element.FieldByIndex(t[i]).Addr().Interface()
+	}
+	return pointers
+}
+
+// getFieldMap returns a map of lowercased column names to the index of
+// the field in :structType they should be scanned into
+func getFieldMap(structType reflect.Type) map[string][]int {
+	if cached, ok := fieldMaps.Load(structType); ok {
+		return cached.(map[string][]int)
+	}
+	fieldMap := map[string][]int{}
+	addFields(fieldMap, structType, nil)
+	fieldMaps.Store(structType, fieldMap)
+	return fieldMap
+}
+
+func addFields(fieldMap map[string][]int, structType reflect.Type, parentIndex []int) {
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		tag := field.Tag.Get(StructTag)
+		if tag == "-" || (field.PkgPath != "" && !field.Anonymous) {
+			continue
+		}
+		index := append(append([]int{}, parentIndex...), i)
+		if field.Anonymous && tag == "" && field.Type.Kind() == reflect.Struct {
+			addFields(fieldMap, field.Type, index)
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		name := tag
+		if stringNotSet(name) {
+			name = field.Name
+		}
+		name = strings.ToLower(name)
+		if _, exists := fieldMap[name]; !exists || len(parentIndex) == 0 {
+			fieldMap[name] = index
+		}
+	}
+}
+
+// QueryStructs runs the :query parameter using the DefaultStructScanner
+// and scans all returned rows into the :dest parameter
+func QueryStructs(ctx context.Context, connection Queryer, query string, dest interface{}, args ...interface{}) error {
+	return DefaultStructScanner.QueryStructs(ctx, connection, query, dest, args...)
+}
+
+// QueryOne runs the :query parameter using the DefaultStructScanner and
+// scans the first returned row into the :dest parameter
+func QueryOne(ctx context.Context, connection Queryer, query string, dest interface{}, args ...interface{}) error {
+	return DefaultStructScanner.QueryOne(ctx, connection, query, dest, args...)
+}
+
+// ScanStructs scans all remaining rows in the :rows parameter into the
+// :dest parameter using the DefaultStructScanner
+func ScanStructs(rows *sql.Rows, dest interface{}) error {
+	return DefaultStructScanner.ScanStructs(rows, dest)
+}
@@ -0,0 +1,143 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/suite"
+)
+
+type ScanTests struct {
+	suite.Suite
+}
+
+func TestScan(t *testing.T) {
+	suite.Run(t, &ScanTests{})
+}
+
+type scanTestsTimestamps struct {
+	CreatedAt time.Time `db:"created_at"`
+}
+
+type scanTestsUser struct {
+	ID       int64   `db:"id"`
+	Name     string  `db:"name"`
+	Nickname *string `db:"nickname"`
+	Ignored  string  `db:"-"`
+	Email    string
+	scanTestsTimestamps
+}
+
+func (s *ScanTests) TestQueryStructs() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	now := time.Now()
+	mock.ExpectQuery("SELECT (.+) FROM users").WithArgs(1).WillReturnRows(
+		sqlmock.NewRows([]string{"id", "name", "nickname", "email", "created_at", "unmapped"}).
+			AddRow(1, "alice", "al", "alice@example.com", now, "x").
+			AddRow(2, "bob", nil, "bob@example.com", now, "y"),
+	)
+	var users []scanTestsUser
+	err = QueryStructs(context.Background(), connection, "SELECT * FROM users WHERE org = ?", &users, 1)
+	s.Nil(err)
+	s.Len(users, 2)
+	s.Equal(int64(1), users[0].ID)
+	s.Equal("alice", users[0].Name)
+	s.Equal("al", *users[0].Nickname)
+	s.Equal("alice@example.com", users[0].Email)
+	s.Equal(now, users[0].CreatedAt)
+	s.Nil(users[1].Nickname)
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *ScanTests) TestQueryStructs_pointers() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	mock.ExpectQuery("SELECT (.+) FROM users").WillReturnRows(
+		sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "alice"),
+	)
+	var users []*scanTestsUser
+	s.Nil(QueryStructs(context.Background(), connection, "SELECT id, name FROM users", &users))
+	s.Len(users, 1)
+	s.Equal("alice", users[0].Name)
+}
+
+func (s *ScanTests) TestQueryStructs_reusedDestination() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	mock.ExpectQuery("SELECT (.+) FROM users").WillReturnRows(
+		sqlmock.NewRows([]string{"id", "name"}).AddRow(2, "bob"),
+	)
+	mock.ExpectQuery("SELECT (.+) FROM users").WillReturnRows(
+		sqlmock.NewRows([]string{"id", "name"}),
+	)
+	stale := scanTestsUser{ID: 1, Name: "alice"}
+	users := []scanTestsUser{stale}
+	previous := users
+	s.Nil(QueryStructs(context.Background(), connection, "SELECT id, name FROM users", &users))
+	s.Len(users, 1)
+	s.Equal("bob", users[0].Name)
+	s.Equal(stale, previous[0])
+	s.Nil(QueryStructs(context.Background(), connection, "SELECT id, name FROM users", &users))
+	s.Empty(users)
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *ScanTests) TestQueryStructs_strict() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	mock.ExpectQuery("SELECT (.+) FROM users").WillReturnRows(
+		sqlmock.NewRows([]string{"id", "unmapped"}).AddRow(1, "x"),
+	)
+	var users []scanTestsUser
+	err = StructScanner{Strict: true}.QueryStructs(context.Background(), connection, "SELECT * FROM users", &users)
+	s.NotNil(err)
+	s.Contains(err.Error(), "'unmapped'")
+}
+
+func (s *ScanTests) TestQueryStructs_invalidDestination() {
+	connection, _, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	var users []scanTestsUser
+	err = QueryStructs(context.Background(), connection, "SELECT * FROM users", users)
+	s.Contains(err.Error(), "non-nil pointer to a slice")
+	var names []string
+	err = QueryStructs(context.Background(), connection, "SELECT * FROM users", &names)
+	s.Contains(err.Error(), "slice of structs")
+}
+
+func (s *ScanTests) TestQueryOne() {
+	connection, mock, err := sqlmock.New()
+	s.Nil(err)
+	defer connection.Close()
+	mock.ExpectQuery("SELECT (.+) FROM users").WithArgs(2).WillReturnRows(
+		sqlmock.NewRows([]string{"id", "name"}).AddRow(2, "bob"),
+	)
+	mock.ExpectQuery("SELECT (.+) FROM users").WithArgs(3).WillReturnRows(
+		sqlmock.NewRows([]string{"id", "name"}),
+	)
+	var user scanTestsUser
+	s.Nil(QueryOne(context.Background(), connection, "SELECT id, name FROM users WHERE id = ?", &user, 2))
+	s.Equal("bob", user.Name)
+	err = QueryOne(context.Background(), connection, "SELECT id, name FROM users WHERE id = ?", &user, 3)
+	s.Equal(sql.ErrNoRows, err)
+	s.Nil(mock.ExpectationsWereMet())
+}
+
+func (s *ScanTests) Test_getFieldMap() {
+	fieldMap := getFieldMap(reflect.TypeOf(scanTestsUser{}))
+	s.Equal([]int{0}, fieldMap["id"])
+	s.Equal([]int{4}, fieldMap["email"])
+	s.Equal([]int{5, 0}, fieldMap["created_at"])
+	_, exists := fieldMap["ignored"]
+	s.False(exists)
+}
@@ -0,0 +1,220 @@
+package db
+
+import "strings"
+
+const (
+	// TokenText is a run of SQL which is not any of the other token types
+	TokenText TokenKind = iota
+	// TokenString is a string literal such as 'value' or $tag$value$tag$,
+	// double-quoted values are string literals on MySQL
+	TokenString
+	// TokenIdentifier is a quoted identifier such as "name", `name` or [name]
+	TokenIdentifier
+	// TokenComment is a -- line comment, a /* block */ comment or a # line
+	// comment on MySQL
+	TokenComment
+	// TokenPlaceholder is a ? positional placeholder
+	TokenPlaceholder
+	// TokenNamed is a :name named parameter
+	TokenNamed
+	// TokenEscapedPlaceholder is a ?? escaped question mark, which Rebind
+	// and Named write as a literal ? (eg. for the ?| operator of PostgreSQL)
+	TokenEscapedPlaceholder
+)
+
+// TokenKind identifies the kind of a token produced by Tokenize
+type TokenKind int
+
+// Token is a fragment of a SQL query
+type Token struct {
+	// Kind is the kind of the fragment
+	Kind TokenKind
+	// Value is the fragment as it appears in the query
+	Value string
+}
+
+// Tokenize splits the :query parameter into tokens so that placeholders,
+// delimiters and keywords can be found without matching characters inside
+// string literals, quoted identifiers and comments, concatenating the
+// values of the tokens returns the query. The lexical rules of the driver
+// named :driver are followed:
+//
+//   - MySQL recognises backslash escapes in string literals, double-quoted
+//     string literals and # line comments
+//   - PostgreSQL recognises dollar-quoted string literals, backslash escapes
+//     in E'escape' string literals and nested block comments
+//   - Microsoft SQL Server recognises [bracketed] identifiers
+func Tokenize(driver, query string) []Token {
+	var tokens []Token
+	backslashEscapes := driver == DriverMySQL
+	textStart := 0
+	addToken := func(kind TokenKind, start, end int) {
+		if textStart < start {
+			tokens = append(tokens, Token{Kind: TokenText, Value: query[textStart:start]})
+		}
+		tokens = append(tokens, Token{Kind: kind, Value: query[start:end]})
+		textStart = end
+	}
+	for i := 0; i < len(query); {
+		switch character := query[i]; {
+		case character == '\'' && driver == DriverPostgreSQL && isEscapeStringPrefix(query, i):
+			end := scanQuoted(query, i, '\'', true)
+			addToken(TokenString, i, end)
+			i = end
+		case character == '\'':
+			end := scanQuoted(query, i, '\'', backslashEscapes)
+			addToken(TokenString, i, end)
+			i = end
+		case character == '"' && driver == DriverMySQL:
+			end := scanQuoted(query, i, '"', backslashEscapes)
+			addToken(TokenString, i, end)
+			i = end
+		case character == '"' || character == '`':
+			end := scanQuoted(query, i, character, false)
+			addToken(TokenIdentifier, i, end)
+			i = end
+		case character == '[' && driver == DriverMSSQL:
+			end := scanQuoted(query, i, ']', false)
+			addToken(TokenIdentifier, i, end)
+			i = end
+		case (character == '-' && strings.HasPrefix(query[i:], "--")) || (character == '#' && driver == DriverMySQL):
+			end := strings.IndexByte(query[i:], '\n')
+			if end == -1 {
+				end = len(query)
+			} else {
+				end += i
+			}
+			addToken(TokenComment, i, end)
+			i = end
+		case character == '/' && strings.HasPrefix(query[i:], "/*"):
+			end := scanBlockComment(query, i, driver == DriverPostgreSQL)
+			addToken(TokenComment, i, end)
+			i = end
+		case character == '$' && driver == DriverPostgreSQL && (i == 0 || !isIdentifierPart(query[i-1])):
+			if tag, ok := scanDollarTag(query[i:]); ok {
+				end := strings.Index(query[i+len(tag):], tag)
+				if end == -1 {
+					end = len(query)
+				} else {
+					end += i + 2*len(tag)
+				}
+				addToken(TokenString, i, end)
+				i = end
+				continue
+			}
+			i++
+		case character == '?' && i+1 < len(query) && query[i+1] == '?':
+			addToken(TokenEscapedPlaceholder, i, i+2)
+			i += 2
+		case character == '?':
+			addToken(TokenPlaceholder, i, i+1)
+			i++
+		case character == ':' && (i == 0 || query[i-1] != ':') && i+1 < len(query) && isNameStart(query[i+1]):
+			end := i + 2
+			for end < len(query) && isNamePart(query[end]) {
+				end++
+			}
+			addToken(TokenNamed, i, end)
+			i = end
+		default:
+			i++
+		}
+	}
+	if textStart < len(query) {
+		tokens = append(tokens, Token{Kind: TokenText, Value: query[textStart:]})
+	}
+	return tokens
+}
+
+// scanQuoted returns the index after the closing :quote character of the
+// quoted section starting at the :start index of the :query parameter,
+// doubled closing characters are treated as escaped
+func scanQuoted(query string, start int, quote byte, backslashEscapes bool) int {
+	for i := start + 1; i < len(query); i++ {
+		switch query[i] {
+		case '\\':
+			if backslashEscapes {
+				i++
+			}
+		case quote:
+			if i+1 < len(query) && query[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(query)
+}
+
+// scanBlockComment returns the index after the block comment starting at
+// the :start index of the :query parameter, block comments inside it are
+// only matched up if the :nested parameter is true
+func scanBlockComment(query string, start int, nested bool) int {
+	depth := 0
+	for i := start; i < len(query)-1; i++ {
+		switch {
+		case query[i] == '/' && query[i+1] == '*':
+			if depth == 0 || nested {
+				depth++
+			}
+			i++
+		case query[i] == '*' && query[i+1] == '/':
+			depth--
+			i++
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return len(query)
+}
+
+// scanDollarTag returns the opening tag of a PostgreSQL dollar-quoted
+// string (eg. $$ or $body$) at the start of the :query parameter
+func scanDollarTag(query string) (string, bool) {
+	for i := 1; i < len(query); i++ {
+		character := query[i]
+		switch {
+		case character == '$':
+			return query[:i+1], true
+		case character == '_' ||
+			(character >= 'a' && character <= 'z') ||
+			(character >= 'A' && character <= 'Z') ||
+			(i > 1 && character >= '0' && character <= '9'):
+			continue
+		}
+		return "", false
+	}
+	return "", false
+}
+
+// isEscapeStringPrefix returns true if the quote at the :start index of the
+// :query parameter is preceded by the E of a PostgreSQL escape string
+// literal (eg. E'a\'b'), which is not part of a longer identifier
+func isEscapeStringPrefix(query string, start int) bool {
+	if start < 1 || (query[start-1] != 'E' && query[start-1] != 'e') {
+		return false
+	}
+	return start < 2 || !isIdentifierPart(query[start-2])
+}
+
+// isIdentifierPart returns true if the :character parameter can be part of
+// an unquoted PostgreSQL identifier, which a dollar quote cannot follow
+func isIdentifierPart(character byte) bool {
+	return isNamePart(character) || character == '$' || character >= 0x80
+}
+
+// isNameStart returns true if the :character parameter can start the name
+// of a named parameter
+func isNameStart(character byte) bool {
+	return character == '_' ||
+		(character >= 'a' && character <= 'z') ||
+		(character >= 'A' && character <= 'Z')
+}
+
+// isNamePart returns true if the :character parameter can be part of the
+// name of a named parameter
+func isNamePart(character byte) bool {
+	return isNameStart(character) || (character >= '0' && character <= '9')
+}
@@ -0,0 +1,89 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type TokenizerTests struct {
+	suite.Suite
+}
+
+func TestTokenizer(t *testing.T) {
+	suite.Run(t, &TokenizerTests{})
+}
+
+func (s *TokenizerTests) Test_Tokenize() {
+	tokens := Tokenize(DriverPostgreSQL, `SELECT "a?" FROM t -- why?
+WHERE b = '?' AND c = ? /* ? */ AND d = $$?$$`)
+	var kinds []TokenKind
+	for i := 0; i < len(tokens); i++ {
+		kinds = append(kinds, tokens[i].Kind)
+	}
+	s.Equal([]TokenKind{
+		TokenText, TokenIdentifier, TokenText, TokenComment,
+		TokenText, TokenString, TokenText, TokenPlaceholder,
+		TokenText, TokenComment, TokenText, TokenString,
+	}, kinds)
+	s.Equal("-- why?", tokens[3].Value)
+	s.Equal("$$?$$", tokens[11].Value)
+}
+
+func (s *TokenizerTests) TestTokenize_backslashEscapes() {
+	tokens := Tokenize(DriverMySQL, `SELECT '\'?' , ?`)
+	s.Equal(`'\'?'`, tokens[1].Value)
+	s.Equal(TokenPlaceholder, tokens[3].Kind)
+	tokens = Tokenize(DriverPostgreSQL, `SELECT 'C:\' , ?`)
+	s.Equal(`'C:\'`, tokens[1].Value)
+	s.Equal(TokenPlaceholder, tokens[3].Kind)
+	tokens = Tokenize(DriverPostgreSQL, `SELECT E'\'?', e'\\', ?`)
+	s.Equal(`'\'?'`, tokens[1].Value)
+	s.Equal(`'\\'`, tokens[3].Value)
+	s.Equal(TokenPlaceholder, tokens[5].Kind)
+	tokens = Tokenize(DriverPostgreSQL, `SELECT type'C:\', ?`)
+	s.Equal(`'C:\'`, tokens[1].Value)
+	s.Equal(TokenPlaceholder, tokens[3].Kind)
+}
+
+func (s *TokenizerTests) TestTokenize_doubledQuotes() {
+	tokens := Tokenize(DriverMSSQL, `SELECT 'it''s?', [a]]?] FROM t WHERE x = ?`)
+	s.Equal(`'it''s?'`, tokens[1].Value)
+	s.Equal(`[a]]?]`, tokens[3].Value)
+	s.Equal(TokenPlaceholder, tokens[5].Kind)
+}
+
+func (s *TokenizerTests) TestTokenize_comments() {
+	tokens := Tokenize(DriverMySQL, "SELECT 1 # why?\nFROM t /* a /* b */ WHERE c = ?")
+	s.Equal("# why?", tokens[1].Value)
+	s.Equal(TokenComment, tokens[1].Kind)
+	s.Equal("/* a /* b */", tokens[3].Value)
+	s.Equal(TokenPlaceholder, tokens[5].Kind)
+	tokens = Tokenize(DriverPostgreSQL, "SELECT 1 /* a /* b */ ? */ # ?")
+	s.Equal("/* a /* b */ ? */", tokens[1].Value)
+	s.Equal(TokenPlaceholder, tokens[3].Kind)
+}
+
+func (s *TokenizerTests) TestTokenize_mysqlDoubleQuotes() {
+	tokens := Tokenize(DriverMySQL, `SELECT "a\"?" , ?`)
+	s.Equal(`"a\"?"`, tokens[1].Value)
+	s.Equal(TokenString, tokens[1].Kind)
+	s.Equal(TokenPlaceholder, tokens[3].Kind)
+	tokens = Tokenize(DriverPostgreSQL, `SELECT "a\" , ?`)
+	s.Equal(`"a\"`, tokens[1].Value)
+	s.Equal(TokenIdentifier, tokens[1].Kind)
+}
+
+func (s *TokenizerTests) TestTokenize_dollarQuotes() {
+	tokens := Tokenize(DriverPostgreSQL, "SELECT a$b$ FROM t WHERE c = ? AND d = $b$?$b$")
+	s.Equal(TokenPlaceholder, tokens[1].Kind)
+	s.Equal("$b$?$b$", tokens[3].Value)
+}
+
+func (s *TokenizerTests) Test_scanDollarTag() {
+	tag, ok := scanDollarTag("$body$ SELECT 1 $body$")
+	s.True(ok)
+	s.Equal("$body$", tag)
+	_, ok = scanDollarTag("$1")
+	s.False(ok)
+}